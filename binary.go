@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// BinaryMirror selects how binary stdout is mirrored.
+type BinaryMirror int
+
+const (
+	// BinaryMirrorSuppress does not mirror the binary data at all.
+	BinaryMirrorSuppress BinaryMirror = iota
+	// BinaryMirrorHexDump mirrors the binary data as a hex dump.
+	BinaryMirrorHexDump
+)
+
+// SetBinaryOutput switches stdout of the process to binary mode. The data is not mirrored as text lines, which
+// mangles binary content, but is either suppressed or hex dumped. The accumulated stdout is byte exact and can be
+// consumed with NewStdOutReader or Snapshot. It should be called before the process is started.
+func (p *Process) SetBinaryOutput(mirror BinaryMirror) {
+	switch mirror {
+	case BinaryMirrorHexDump:
+		p.stdout.out = &hexDumper{out: p.printer}
+	default:
+		p.stdout.out = io.Discard
+	}
+}
+
+// hexDumper writes hex dump of every chunk to out.
+type hexDumper struct {
+	out io.Writer
+}
+
+func (h *hexDumper) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if _, err := io.WriteString(h.out, strings.TrimSuffix(hex.Dump(p), "\n")); err != nil {
+		return 0, err
+	}
+	// The dump is complete, it is not held by the Redactor of the printer.
+	if f, ok := h.out.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
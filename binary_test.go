@@ -0,0 +1,31 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryOutputIsByteExact(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "printf", `\000\001\377\n\r\002`)
+	require.NoError(t, err)
+	var console bytes.Buffer
+	p.printer.Out = &console
+	p.SetBinaryOutput(BinaryMirrorHexDump)
+
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	wg.Wait()
+
+	data, err := io.ReadAll(p.NewStdOutReader())
+	require.NoError(t, err)
+	expected := []byte{0x00, 0x01, 0xff, '\n', '\r', 0x02}
+	require.Equal(t, expected, data)
+	require.Equal(t, expected, p.stdout.Snapshot())
+	require.Contains(t, console.String(), "00 01 ff 0a 0d 02")
+}
@@ -1,30 +1,119 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"sync"
+	"time"
 )
 
+// DefaultWakeInterval is the default interval at which readers blocked in ReadContext check their context.
+const DefaultWakeInterval = 50 * time.Millisecond
+
+// ErrDataDropped is returned by a reader of the buffer in ErrorMode, when the data it has not read yet is dropped.
+var ErrDataDropped = errors.New("data dropped before it was read")
+
 // MultiReaderBuffer is a thread safe memory buffer with one writer and multiple readers. I.e., it is
 // possible to read the same buffer from start or continue reading while writing. Calling Close notifies all
 // open and future readers that data is finalized and no more write operations are expected.
 type MultiReaderBuffer interface {
 	io.WriteCloser
 	NewReader() io.ReadCloser
+	// NewReaderAt returns a reader that starts reading at a given byte offset.
+	NewReaderAt(offset int) io.ReadCloser
+	// Len returns the total number of bytes written so far, including bytes no longer retained.
+	Len() int
+	// Snapshot returns a copy of the retained data. It does not block.
+	Snapshot() []byte
+	// CloseAllReaders closes all open readers, e.g. to evict a wedged consumer. Their pending and future reads
+	// return io.ErrClosedPipe.
+	CloseAllReaders()
+}
+
+// ContextReader is implemented by the readers of MultiReaderBuffer. ReadContext is the same as Read, but a blocked
+// call returns the context error when ctx is done.
+type ContextReader interface {
+	ReadContext(ctx context.Context, p []byte) (int, error)
 }
 
+// SkipCounter is implemented by the readers of MultiReaderBuffer. Skipped returns the number of bytes the reader
+// skipped in SkipMode, because they were dropped before it read them.
+type SkipCounter interface {
+	Skipped() int
+}
+
+// OverrunMode selects what a reader gets when the data it has not read yet is dropped, see OnOverrun.
+type OverrunMode int
+
+const (
+	// SkipMode continues reading from the oldest retained byte, see SkipCounter. It is the default.
+	SkipMode OverrunMode = iota
+	// ErrorMode fails the read with ErrDataDropped. The reader stays at its position, so further reads fail too.
+	ErrorMode
+)
+
 // multiReaderBuffer implements thread safe memory buffer with one writer and multiple readers.
 type multiReaderBuffer struct {
 	m      sync.Mutex
 	cv     *sync.Cond
 	buf    []byte
 	closed bool
+	// start is the offset of buf[0] from the beginning of the data, it is non-zero when old data is dropped.
+	start        int
+	wakeInterval time.Duration
+	// maxLines is the number of complete lines to retain, zero means unlimited. newlines counts complete lines
+	// in buf.
+	maxLines int
+	newlines int
+	overrun  OverrunMode
+	// readers are the open readers. A reader is removed when it is closed or reaches the end of the closed buffer.
+	readers map[*multiBufferReader]struct{}
+}
+
+// BufferOption configures MultiReaderBuffer.
+type BufferOption func(*multiReaderBuffer)
+
+// WithWakeInterval sets how often readers blocked in ReadContext wake up to check their context. Shorter interval
+// reduces the latency of the cancellation at the cost of CPU. The default is DefaultWakeInterval, it is also used
+// if d is not positive.
+func WithWakeInterval(d time.Duration) BufferOption {
+	return func(b *multiReaderBuffer) {
+		if d <= 0 {
+			d = DefaultWakeInterval
+		}
+		b.wakeInterval = d
+	}
+}
+
+// WithMaxLines limits the buffer to the n most recent complete lines, older lines are dropped. An incomplete
+// trailing line, i.e. data after the last new line character, is always retained and is not counted. Readers that
+// fall behind the retained data continue from the oldest retained line, unless configured otherwise with OnOverrun.
+func WithMaxLines(n int) BufferOption {
+	return func(b *multiReaderBuffer) {
+		b.maxLines = n
+	}
+}
+
+// OnOverrun sets what a reader that falls behind the retained data gets, see OverrunMode. With SkipMode the reader
+// silently loses the dropped lines: it continues from the start of a line, but a keyword in the dropped lines is
+// never matched and a sequence of lines may have gaps. Use ErrorMode when the complete output matters.
+func OnOverrun(mode OverrunMode) BufferOption {
+	return func(b *multiReaderBuffer) {
+		b.overrun = mode
+	}
 }
 
 // NewMultiReaderBuffer returns new instance of MultiReaderBuffer
-func NewMultiReaderBuffer() MultiReaderBuffer {
-	r := &multiReaderBuffer{}
+func NewMultiReaderBuffer(opts ...BufferOption) MultiReaderBuffer {
+	r := &multiReaderBuffer{
+		wakeInterval: DefaultWakeInterval,
+	}
 	r.cv = sync.NewCond(&r.m)
+	for _, opt := range opts {
+		opt(r)
+	}
 	return r
 }
 
@@ -34,18 +123,94 @@ func (b *multiReaderBuffer) Write(p []byte) (int, error) {
 		return 0, nil
 	}
 	b.cv.L.Lock()
+	defer b.cv.L.Unlock()
 	if b.closed {
 		return 0, io.ErrClosedPipe
 	}
 	b.buf = append(b.buf, p...)
+	if b.maxLines > 0 {
+		b.newlines += bytes.Count(p, []byte{'\n'})
+		b.dropLines()
+	}
 	b.cv.Broadcast()
-	b.cv.L.Unlock()
 	return len(p), nil
 }
 
+// dropLines drops the oldest lines until the limit is satisfied. It should be called with the lock held.
+func (b *multiReaderBuffer) dropLines() {
+	for b.newlines > b.maxLines {
+		i := bytes.IndexByte(b.buf, '\n')
+		b.buf = b.buf[i+1:]
+		b.start += i + 1
+		b.newlines--
+	}
+}
+
 // NewReader returns new instance of Reader for the buffer.
 func (b *multiReaderBuffer) NewReader() io.ReadCloser {
-	return &multiBufferReader{source: b}
+	return b.register(&multiBufferReader{source: b})
+}
+
+// NewReaderAt returns new instance of Reader that skips first offset bytes of the buffer. If the offset is beyond
+// the data written so far, the reader waits until the buffer grows past it.
+func (b *multiReaderBuffer) NewReaderAt(offset int) io.ReadCloser {
+	return b.register(&multiBufferReader{source: b, offset: max(offset, 0)})
+}
+
+// register adds the reader to the open readers.
+func (b *multiReaderBuffer) register(r *multiBufferReader) *multiBufferReader {
+	b.cv.L.Lock()
+	defer b.cv.L.Unlock()
+	if b.readers == nil {
+		b.readers = map[*multiBufferReader]struct{}{}
+	}
+	b.readers[r] = struct{}{}
+	return r
+}
+
+// CloseAllReaders closes all open readers and wakes up the blocked ones.
+func (b *multiReaderBuffer) CloseAllReaders() {
+	b.cv.L.Lock()
+	defer b.cv.L.Unlock()
+	for r := range b.readers {
+		r.closed = true
+	}
+	clear(b.readers)
+	b.cv.Broadcast()
+}
+
+// Len returns the total number of bytes written so far.
+func (b *multiReaderBuffer) Len() int {
+	b.cv.L.Lock()
+	defer b.cv.L.Unlock()
+	return b.start + len(b.buf)
+}
+
+// Snapshot returns a copy of the retained data.
+func (b *multiReaderBuffer) Snapshot() []byte {
+	b.cv.L.Lock()
+	defer b.cv.L.Unlock()
+	return append([]byte(nil), b.buf...)
+}
+
+// startWaker periodically wakes up all blocked readers until the returned function is called.
+func (b *multiReaderBuffer) startWaker() func() {
+	stop := make(chan struct{})
+	go func() {
+		t := time.NewTicker(b.wakeInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				b.cv.L.Lock()
+				b.cv.Broadcast()
+				b.cv.L.Unlock()
+			}
+		}
+	}()
+	return func() { close(stop) }
 }
 
 // Close closes the writer and notifies all open and future readers that data is finalized.
@@ -58,47 +223,102 @@ func (b *multiReaderBuffer) Close() error {
 }
 
 type multiBufferReader struct {
-	source *multiReaderBuffer
-	offset int
-	closed bool
+	source  *multiReaderBuffer
+	offset  int
+	closed  bool
+	skipped int
 }
 
 // Read reads chunk from buffer. Blocks if there is no data to read, but the source is open.
 // Read reads chunk from buffer. When reader is done with reading the
 // content of the buffer, it will block until either more data arrives
-// or buffer closed.
+// or buffer closed. Every call takes the lock of the buffer, so reading in tiny pieces, e.g. one byte at a time, is
+// correct, but slow; wrap the reader in bufio.Reader instead.
 func (r *multiBufferReader) Read(p []byte) (int, error) {
+	return r.read(nil, p)
+}
+
+// ReadContext is the same as Read, but when blocked it returns ctx.Err() soon after ctx is done. The blocked reader
+// checks the context with the wake interval of the buffer.
+func (r *multiBufferReader) ReadContext(ctx context.Context, p []byte) (int, error) {
+	return r.read(ctx, p)
+}
+
+// read implements Read and ReadContext, ctx can be nil.
+func (r *multiBufferReader) read(ctx context.Context, p []byte) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
 	n := 0
 	sourceClosed := false
+	var stopWaker func()
 	r.source.cv.L.Lock()
 	for {
 		if r.closed {
 			r.source.cv.L.Unlock()
+			if stopWaker != nil {
+				stopWaker()
+			}
 			return 0, io.ErrClosedPipe
 		}
-		n = copy(p, r.source.buf[r.offset:])
+		if ctx != nil && ctx.Err() != nil {
+			r.source.cv.L.Unlock()
+			if stopWaker != nil {
+				stopWaker()
+			}
+			return 0, ctx.Err()
+		}
+		if r.offset < r.source.start {
+			if r.source.overrun == ErrorMode {
+				r.source.cv.L.Unlock()
+				if stopWaker != nil {
+					stopWaker()
+				}
+				return 0, ErrDataDropped
+			}
+			// The data was dropped, continue from the oldest retained byte.
+			r.skipped += r.source.start - r.offset
+			r.offset = r.source.start
+		}
+		if pos := r.offset - r.source.start; pos < len(r.source.buf) {
+			n = copy(p, r.source.buf[pos:])
+		}
 		sourceClosed = r.source.closed
 		if n == 0 && !sourceClosed {
+			if ctx != nil && stopWaker == nil {
+				stopWaker = r.source.startWaker()
+			}
 			r.source.cv.Wait()
 		} else {
 			break
 		}
 	}
 	r.source.cv.L.Unlock()
+	if stopWaker != nil {
+		stopWaker()
+	}
 	if n == 0 {
+		r.source.cv.L.Lock()
+		delete(r.source.readers, r)
+		r.source.cv.L.Unlock()
 		return 0, io.EOF
 	}
 	r.offset += n
 	return n, nil
 }
 
+// Skipped returns the number of bytes skipped because they were dropped before they were read.
+func (r *multiBufferReader) Skipped() int {
+	r.source.cv.L.Lock()
+	defer r.source.cv.L.Unlock()
+	return r.skipped
+}
+
 // Close closes the reader
 func (r *multiBufferReader) Close() error {
 	r.source.cv.L.Lock()
 	r.closed = true
+	delete(r.source.readers, r)
 	r.source.cv.Broadcast()
 	r.source.cv.L.Unlock()
 	return nil
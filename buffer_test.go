@@ -1,9 +1,11 @@
 package runner
 
 import (
+	"context"
 	"io"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -165,3 +167,125 @@ func TestReadBlocksUntilReaderClosed(t *testing.T) {
 	// wait for the reader to finish
 	wg.Wait()
 }
+
+func TestReadContextUnblocksAfterCancel(t *testing.T) {
+	buf := NewMultiReaderBuffer(WithWakeInterval(20 * time.Millisecond))
+	reader := buf.NewReader().(ContextReader)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	result := make(chan error)
+	go func() {
+		_, err := reader.ReadContext(ctx, make([]byte, 10))
+		result <- err
+	}()
+
+	// Let the reader block before cancelling.
+	time.Sleep(50 * time.Millisecond)
+	cancelled := time.Now()
+	cancel()
+	require.ErrorIs(t, <-result, context.Canceled)
+	require.Less(t, time.Since(cancelled), 200*time.Millisecond)
+}
+
+func TestWakeIntervalNotPositive(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		buf := NewMultiReaderBuffer(WithWakeInterval(d))
+		reader := buf.NewReader().(ContextReader)
+		ctx, cancel := context.WithTimeout(context.TODO(), 2*DefaultWakeInterval)
+		_, err := reader.ReadContext(ctx, make([]byte, 10))
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
+
+func TestMaxLinesKeepsMostRecent(t *testing.T) {
+	buf := NewMultiReaderBuffer(WithMaxLines(3))
+	for _, s := range []string{"one\ntwo\n", "three\nfour\nfi", "ve\nsix\n"} {
+		_, err := buf.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	require.Equal(t, "four\nfive\nsix\n", string(buf.Snapshot()))
+	require.Equal(t, len("one\ntwo\nthree\nfour\nfive\nsix\n"), buf.Len())
+
+	// Incomplete line is retained without dropping one more line.
+	_, err := buf.Write([]byte("sev"))
+	require.NoError(t, err)
+	require.NoError(t, buf.Close())
+
+	data, err := io.ReadAll(buf.NewReader())
+	require.NoError(t, err)
+	require.Equal(t, "four\nfive\nsix\nsev", string(data))
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	buf := NewMultiReaderBuffer()
+	require.NoError(t, buf.Close())
+	_, err := buf.Write([]byte("late"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	// The buffer is still usable after the failed write.
+	require.Empty(t, buf.Snapshot())
+}
+
+func TestOverrunSkipMode(t *testing.T) {
+	buf := NewMultiReaderBuffer(WithMaxLines(2))
+	slow := buf.NewReader()
+	_, err := buf.Write([]byte("one\ntwo\nthree\nfour\nfive\n"))
+	require.NoError(t, err)
+	require.NoError(t, buf.Close())
+
+	data, err := io.ReadAll(slow)
+	require.NoError(t, err)
+	require.Equal(t, "four\nfive\n", string(data))
+	require.Equal(t, len("one\ntwo\nthree\n"), slow.(SkipCounter).Skipped())
+}
+
+func TestOverrunErrorMode(t *testing.T) {
+	buf := NewMultiReaderBuffer(WithMaxLines(2), OnOverrun(ErrorMode))
+	slow := buf.NewReader()
+	fast := buf.NewReader()
+	_, err := buf.Write([]byte("one\ntwo\n"))
+	require.NoError(t, err)
+	p := make([]byte, 3)
+	n, err := slow.Read(p)
+	require.NoError(t, err)
+	require.Equal(t, "one", string(p[:n]))
+	data := make([]byte, 100)
+	n, err = fast.Read(data)
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\n", string(data[:n]))
+
+	_, err = buf.Write([]byte("three\nfour\n"))
+	require.NoError(t, err)
+	_, err = slow.Read(p)
+	require.ErrorIs(t, err, ErrDataDropped)
+	_, err = slow.Read(p)
+	require.ErrorIs(t, err, ErrDataDropped)
+
+	// The reader that kept up is not affected.
+	n, err = fast.Read(data)
+	require.NoError(t, err)
+	require.Equal(t, "three\nfour\n", string(data[:n]))
+}
+
+func TestCloseAllReaders(t *testing.T) {
+	buf := NewMultiReaderBuffer()
+	blocked := buf.NewReader()
+	result := make(chan error)
+	go func() {
+		_, err := blocked.Read(make([]byte, 10))
+		result <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	buf.CloseAllReaders()
+	require.ErrorIs(t, <-result, io.ErrClosedPipe)
+
+	// The buffer and the new readers are not affected.
+	_, err := buf.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, buf.Close())
+	data, err := io.ReadAll(buf.NewReader())
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+	_, err = blocked.Read(make([]byte, 10))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
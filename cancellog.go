@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cancelLog collects the names of the processes killed because their context is done, see SetCancelLogWindow.
+var cancelLog struct {
+	m      sync.Mutex
+	window time.Duration
+	names  []string
+	timer  *time.Timer
+}
+
+// SetCancelLogWindow coalesces the log lines of the processes killed because their context is done, e.g. on mass
+// teardown of processes that share a context. Instead of a line per process, a single summary line with the names
+// of the processes killed within the window is logged when the window passes. Zero or negative window restores a
+// line per process, the default.
+func SetCancelLogWindow(window time.Duration) {
+	cancelLog.m.Lock()
+	defer cancelLog.m.Unlock()
+	cancelLog.window = window
+}
+
+// logCancel logs that the process is killed because its context is done.
+func logCancel(shortName string) {
+	cancelLog.m.Lock()
+	defer cancelLog.m.Unlock()
+	if cancelLog.window <= 0 {
+		log.Println("Cancel called for ", shortName)
+		return
+	}
+	cancelLog.names = append(cancelLog.names, shortName)
+	if cancelLog.timer == nil {
+		cancelLog.timer = time.AfterFunc(cancelLog.window, flushCancelLog)
+	}
+}
+
+// flushCancelLog logs the summary line of the processes collected by logCancel.
+func flushCancelLog() {
+	cancelLog.m.Lock()
+	names := cancelLog.names
+	cancelLog.names, cancelLog.timer = nil, nil
+	cancelLog.m.Unlock()
+	log.Printf("Cancel called for %d processes: %s", len(names), strings.Join(names, ", "))
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use, e.g. as the log output.
+type lockedBuffer struct {
+	m sync.Mutex
+	b bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.b.String()
+}
+
+func TestCancelLogWindow(t *testing.T) {
+	var logs lockedBuffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	SetCancelLogWindow(100 * time.Millisecond)
+	t.Cleanup(func() { SetCancelLogWindow(0) })
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	var processes []*Process
+	for range 3 {
+		p, err := NewProcess(ctx, "sleep", "5")
+		require.NoError(t, err)
+		require.NoError(t, p.Start())
+		processes = append(processes, p)
+	}
+	cancel()
+	for _, p := range processes {
+		_ = p.WaitForExit(context.TODO())
+		require.Equal(t, ReasonCanceled, p.ExitReason())
+	}
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), "Cancel called for 3 processes: sleep, sleep, sleep")
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, strings.Count(logs.String(), "Cancel called for"))
+}
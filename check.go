@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCheckFailed is returned by RunAndCheck when the process does not meet the expectations.
+var ErrCheckFailed = errors.New("process check failed")
+
+// RunCheck describes the expected outcome of the process for RunAndCheck.
+type RunCheck struct {
+	// ExitCode is the expected exit code.
+	ExitCode int
+	// StdOutContains are substrings that must be present in stdout.
+	StdOutContains []string
+	// StdErrExcludes are substrings that must not be present in stderr.
+	StdErrExcludes []string
+}
+
+// RunAndCheck runs the command to completion and verifies the exit code and the output. It returns ErrCheckFailed
+// that describes all violated expectations.
+func RunAndCheck(ctx context.Context, opts RunCheck, name string, args ...string) error {
+	p, err := NewProcess(ctx, name, args...)
+	if err != nil {
+		return err
+	}
+	if err := p.Start(); err != nil {
+		return err
+	}
+	<-p.Done()
+
+	var violations []error
+	if code := p.Summary().ExitCode; code != opts.ExitCode {
+		violations = append(violations, fmt.Errorf("exit code is %d, expected %d", code, opts.ExitCode))
+	}
+	stdout := string(p.stdout.Snapshot())
+	for _, s := range opts.StdOutContains {
+		if !strings.Contains(stdout, s) {
+			violations = append(violations, fmt.Errorf("stdout does not contain %q", s))
+		}
+	}
+	stderr := string(p.stderr.Snapshot())
+	for _, s := range opts.StdErrExcludes {
+		if strings.Contains(stderr, s) {
+			violations = append(violations, fmt.Errorf("stderr contains %q", s))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s:\n%w", ErrCheckFailed, p, errors.Join(violations...))
+}
@@ -0,0 +1,27 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAndCheck(t *testing.T) {
+	ctx := context.TODO()
+	require.NoError(t, RunAndCheck(ctx, RunCheck{
+		ExitCode:       0,
+		StdOutContains: []string{"hello", "world"},
+		StdErrExcludes: []string{"error"},
+	}, "bash", "-c", "echo hello world && echo warning 1>&2"))
+
+	err := RunAndCheck(ctx, RunCheck{
+		ExitCode:       0,
+		StdOutContains: []string{"hello", "done"},
+		StdErrExcludes: []string{"error"},
+	}, "bash", "-c", "echo hello && echo error: boom 1>&2 && exit 2")
+	require.ErrorIs(t, err, ErrCheckFailed)
+	require.ErrorContains(t, err, "exit code is 2, expected 0")
+	require.ErrorContains(t, err, `stdout does not contain "done"`)
+	require.ErrorContains(t, err, `stderr contains "error"`)
+}
@@ -0,0 +1,17 @@
+package runner
+
+import "time"
+
+// Clock provides the current time. It allows to control time in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock is the Clock that returns time.Now.
+var SystemClock Clock = systemClock{}
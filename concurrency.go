@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"context"
+	"sync"
+)
+
+// concurrency limits number of processes running at the same time.
+var concurrency struct {
+	m   sync.Mutex
+	sem chan struct{}
+}
+
+// SetMaxConcurrent limits the number of processes that can run at the same time across the whole package.
+// Start blocks when the limit is reached until one of the running processes exits or the process context is
+// done. Zero or negative n removes the limit. Processes that are already running keep their slots.
+func SetMaxConcurrent(n int) {
+	concurrency.m.Lock()
+	defer concurrency.m.Unlock()
+	if n <= 0 {
+		concurrency.sem = nil
+		return
+	}
+	concurrency.sem = make(chan struct{}, n)
+}
+
+// acquireSlot blocks until the process is allowed to start. The returned function releases the slot.
+func acquireSlot(ctx context.Context) (func(), error) {
+	concurrency.m.Lock()
+	sem := concurrency.sem
+	concurrency.m.Unlock()
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxConcurrentSerializesProcesses(t *testing.T) {
+	SetMaxConcurrent(1)
+	t.Cleanup(func() { SetMaxConcurrent(0) })
+
+	ctx := context.TODO()
+	var wg sync.WaitGroup
+	var starters sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		p, err := NewProcess(ctx, "sleep", "0.2")
+		require.NoError(t, err)
+		starters.Add(1)
+		go func() {
+			defer starters.Done()
+			assert.NoError(t, p.StartAsync(&wg))
+		}()
+	}
+	starters.Wait()
+	wg.Wait()
+	require.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond)
+}
+
+func TestMaxConcurrentHonorsContext(t *testing.T) {
+	SetMaxConcurrent(1)
+	t.Cleanup(func() { SetMaxConcurrent(0) })
+
+	var wg sync.WaitGroup
+	p1, err := NewProcess(context.TODO(), "sleep", "0.5")
+	require.NoError(t, err)
+	require.NoError(t, p1.StartAsync(&wg))
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	p2, err := NewProcess(ctx, "sleep", "0.5")
+	require.NoError(t, err)
+	require.ErrorIs(t, p2.Start(), context.DeadlineExceeded)
+	wg.Wait()
+}
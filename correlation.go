@@ -0,0 +1,33 @@
+package runner
+
+import "context"
+
+// correlationKey is the context key of the correlation ID.
+type correlationKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx that carries the correlation ID, e.g. the ID of the trace the test
+// belongs to. See WithContextCorrelationID.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or an empty string.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// WithContextCorrelationID adds the correlation ID carried by the context of the process to each mirrored line, in
+// both the text and the JSON output. Nothing is added if the context has no correlation ID.
+func WithContextCorrelationID() Option {
+	return func(p *Process) error {
+		id := CorrelationID(p.ctx)
+		p.printer.CorrelationID = id
+		for _, o := range []*AccumulatedOutput{p.stdout, p.stderr} {
+			if j, ok := o.out.(*JSONPrinter); ok {
+				j.CorrelationID = id
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextCorrelationID(t *testing.T) {
+	ctx := ContextWithCorrelationID(context.TODO(), "trace-42")
+	require.Equal(t, "trace-42", CorrelationID(ctx))
+	require.Empty(t, CorrelationID(context.TODO()))
+
+	var text bytes.Buffer
+	p, err := NewProcess(ctx, "bash", "-c", "echo hello")
+	require.NoError(t, err)
+	p.SetOutput(&text)
+	require.NoError(t, p.Apply(WithContextCorrelationID()))
+	require.NoError(t, p.Run())
+	require.Contains(t, text.String(), "bash            | correlation_id=trace-42 | hello\n")
+
+	var jsonOut bytes.Buffer
+	p, err = NewProcess(ctx, "bash", "-c", "echo hello")
+	require.NoError(t, err)
+	p.SetJSONOutput(&jsonOut, nil)
+	require.NoError(t, p.Apply(WithContextCorrelationID()))
+	require.NoError(t, p.Run())
+	var record jsonLine
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(jsonOut.String())), &record))
+	require.Equal(t, "trace-42", record.CorrelationID)
+
+	// No correlation ID in the context.
+	text.Reset()
+	p, err = NewProcess(context.TODO(), "bash", "-c", "echo hello")
+	require.NoError(t, err)
+	p.SetOutput(&text)
+	require.NoError(t, p.Apply(WithContextCorrelationID()))
+	require.NoError(t, p.Run())
+	require.Contains(t, text.String(), "bash            | hello\n")
+}
@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadlineReader is a reader of MultiReaderBuffer with read deadline support, similar to net.Conn. A blocked Read
+// fails with os.ErrDeadlineExceeded when the deadline passes. The deadline can be changed while Read is blocked.
+type DeadlineReader struct {
+	reader   contextReadCloser
+	m        sync.Mutex
+	deadline time.Time
+	// changed is closed when deadline is changed.
+	changed chan struct{}
+}
+
+type contextReadCloser interface {
+	io.Closer
+	ContextReader
+}
+
+// NewDeadlineReader returns a reader of the buffer that supports read deadlines. The readers of the buffer must
+// implement ContextReader.
+func NewDeadlineReader(b MultiReaderBuffer) *DeadlineReader {
+	return &DeadlineReader{
+		reader:  b.NewReader().(contextReadCloser),
+		changed: make(chan struct{}),
+	}
+}
+
+// NewDeadlineReader returns a reader of the output that supports read deadlines.
+func (s *AccumulatedOutput) NewDeadlineReader() *DeadlineReader {
+	return NewDeadlineReader(s.buf)
+}
+
+// SetReadDeadline sets the deadline for current and future Read calls. A zero value means Read will not time out.
+func (r *DeadlineReader) SetReadDeadline(t time.Time) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.deadline = t
+	close(r.changed)
+	r.changed = make(chan struct{})
+	return nil
+}
+
+// Read reads from the buffer. It returns os.ErrDeadlineExceeded if the deadline passes.
+func (r *DeadlineReader) Read(p []byte) (int, error) {
+	for {
+		r.m.Lock()
+		deadline, changed := r.deadline, r.changed
+		r.m.Unlock()
+
+		n, err := r.readUntil(deadline, changed, p)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return n, os.ErrDeadlineExceeded
+		}
+		if errors.Is(err, context.Canceled) {
+			// The deadline was changed, try again with the new one.
+			continue
+		}
+		return n, err
+	}
+}
+
+// readUntil reads with a given deadline. It is cancelled when changed is closed.
+func (r *DeadlineReader) readUntil(deadline time.Time, changed <-chan struct{}, p []byte) (int, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if !deadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		defer cancelDeadline()
+	}
+	go func() {
+		select {
+		case <-changed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return r.reader.ReadContext(ctx, p)
+}
+
+// Close closes the reader.
+func (r *DeadlineReader) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineReaderTimesOut(t *testing.T) {
+	buf := NewMultiReaderBuffer(WithWakeInterval(10 * time.Millisecond))
+	_, err := buf.Write([]byte("hello"))
+	require.NoError(t, err)
+	reader := NewDeadlineReader(buf)
+
+	p := make([]byte, 10)
+	n, err := reader.Read(p)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(p[:n]))
+
+	// Nothing to read, the blocked read should time out.
+	require.NoError(t, reader.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	start := time.Now()
+	_, err = reader.Read(p)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+
+	// Clearing the deadline while read is blocked extends it.
+	require.NoError(t, reader.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	go func() {
+		_ = reader.SetReadDeadline(time.Time{})
+		time.Sleep(100 * time.Millisecond)
+		_, _ = buf.Write([]byte("world"))
+	}()
+	n, err = reader.Read(p)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(p[:n]))
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package runner
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readProcs reads the stat of all processes from /proc.
+func readProcs() (map[int]procStat, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	procs := map[int]procStat{}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			// Process is gone already.
+			continue
+		}
+		// Format is "pid (comm) state ppid pgrp session ...", comm can contain spaces and parentheses. The start
+		// time is the 22nd field.
+		s := string(stat)
+		fields := strings.Fields(s[strings.LastIndexByte(s, ')')+1:])
+		if len(fields) < 20 {
+			continue
+		}
+		ppid, err1 := strconv.Atoi(fields[1])
+		pgid, err2 := strconv.Atoi(fields[2])
+		start, err3 := strconv.ParseUint(fields[19], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		procs[pid] = procStat{ppid: ppid, pgid: pgid, start: start, zombie: fields[0] == "Z"}
+	}
+	return procs, nil
+}
@@ -0,0 +1,8 @@
+//go:build unix && !linux
+
+package runner
+
+// readProcs reads the stat of all processes, there is no /proc to walk.
+func readProcs() (map[int]procStat, error) {
+	return readProcsPS()
+}
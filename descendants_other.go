@@ -0,0 +1,13 @@
+//go:build !unix
+
+package runner
+
+import "errors"
+
+// Descendants is supported only on Unix.
+func (p *Process) Descendants() ([]int, error) {
+	return nil, errors.ErrUnsupported
+}
+
+// snapshotDescendants does nothing, Descendants is supported only on Unix.
+func (p *process) snapshotDescendants() {}
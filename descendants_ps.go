@@ -0,0 +1,43 @@
+//go:build unix
+
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readProcsPS reads the stat of all processes from the output of ps, for the systems without /proc. The start time
+// is in seconds.
+func readProcsPS() (map[int]procStat, error) {
+	cmd := exec.Command("ps", "-A", "-o", "pid=,ppid=,pgid=,stat=,lstart=")
+	// The start time is printed in the format of the locale.
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	procs := map[int]procStat{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// Format is "pid ppid pgid stat Mon Jan  2 15:04:05 2006".
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		pgid, err3 := strconv.Atoi(fields[2])
+		start, err4 := time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(fields[4:9], " "), time.Local)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		procs[pid] = procStat{ppid: ppid, pgid: pgid, start: uint64(start.Unix()), zombie: strings.HasPrefix(fields[3], "Z")}
+	}
+	return procs, scanner.Err()
+}
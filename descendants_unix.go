@@ -0,0 +1,83 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"slices"
+)
+
+// Descendants returns PIDs of all descendants of the process, i.e. children, grandchildren and so on, that are
+// alive. It walks the process table, /proc on Linux and the output of ps on other Unix systems, so the result is a
+// snapshot and can be used to verify that no processes outlive the test. The descendants of the process that exits
+// are reparented and cannot be found by the walk, so it also reports the descendants seen earlier, by Descendants or
+// right before a signal is sent to the process, e.g. by Kill, and the members of the process group, see
+// WithProcessGroup. Thus it can be called after the teardown too.
+func (p *Process) Descendants() ([]int, error) {
+	proc := p.osProcess()
+	if proc == nil {
+		return nil, errors.New("process is not running")
+	}
+	procs, err := readProcs()
+	if err != nil {
+		return nil, err
+	}
+	p.recordDescendants(proc.Pid, procs)
+	p.m.Lock()
+	defer p.m.Unlock()
+	var result []int
+	for pid, st := range procs {
+		if pid == proc.Pid || st.zombie {
+			continue
+		}
+		if start, ok := p.descendants[pid]; ok && start == st.start || p.processGroup && st.pgid == proc.Pid {
+			result = append(result, pid)
+		}
+	}
+	slices.Sort(result)
+	return result, nil
+}
+
+// snapshotDescendants records the descendants of the running process, so Descendants finds them after they are
+// reparented.
+func (p *process) snapshotDescendants() {
+	proc := p.osProcess()
+	if proc == nil {
+		return
+	}
+	if procs, err := readProcs(); err == nil {
+		p.recordDescendants(proc.Pid, procs)
+	}
+}
+
+// recordDescendants walks procs from the process with a given pid and records its descendants.
+func (p *process) recordDescendants(pid int, procs map[int]procStat) {
+	children := map[int][]int{}
+	for child, st := range procs {
+		children[st.ppid] = append(children[st.ppid], child)
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.descendants == nil {
+		p.descendants = map[int]uint64{}
+	}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			// The start time tells a descendant from a process that reused its PID.
+			p.descendants[child] = procs[child].start
+			queue = append(queue, child)
+		}
+	}
+}
+
+// procStat is the part of the process table used by Descendants.
+type procStat struct {
+	ppid int
+	pgid int
+	// start is the start time of the process, its unit depends on the platform.
+	start  uint64
+	zombie bool
+}
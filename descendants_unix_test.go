@@ -0,0 +1,81 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescendantsListsGrandchildren(t *testing.T) {
+	ctx := context.TODO()
+	// Subshell forks sleep, so there is a child and a grandchild.
+	p, err := NewProcess(ctx, "bash", "-c", "(sleep 5; true) >/dev/null 2>&1 & echo forked && wait")
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "forked"))
+
+	require.Eventually(t, func() bool {
+		pids, err := p.Descendants()
+		require.NoError(t, err)
+		return len(pids) >= 2
+	}, time.Second, 10*time.Millisecond)
+
+	// Killing bash leaves its descendants running, they are still reported after they are reparented.
+	require.NoError(t, p.Kill())
+	wg.Wait()
+	pids, err := p.Descendants()
+	require.NoError(t, err)
+	require.Len(t, pids, 2)
+	for _, pid := range pids {
+		require.NoError(t, syscall.Kill(pid, syscall.SIGKILL))
+	}
+	require.Eventually(t, func() bool {
+		pids, err := p.Descendants()
+		require.NoError(t, err)
+		return len(pids) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDescendantsAfterTeardown(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "(sleep 5; true) & (sleep 5; true) & echo forked && wait")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithProcessGroup()))
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "forked"))
+	require.Eventually(t, func() bool {
+		pids, err := p.Descendants()
+		require.NoError(t, err)
+		return len(pids) >= 4
+	}, time.Second, 10*time.Millisecond)
+
+	// The group is killed, nothing remains after the teardown.
+	require.NoError(t, p.Kill())
+	<-p.Done()
+	require.Eventually(t, func() bool {
+		pids, err := p.Descendants()
+		require.NoError(t, err)
+		return len(pids) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReadProcsPS(t *testing.T) {
+	procs, err := readProcsPS()
+	require.NoError(t, err)
+	self, ok := procs[os.Getpid()]
+	require.True(t, ok)
+	require.Equal(t, os.Getppid(), self.ppid)
+	pgid, err := syscall.Getpgid(os.Getpid())
+	require.NoError(t, err)
+	require.Equal(t, pgid, self.pgid)
+	require.NotZero(t, self.start)
+	require.False(t, self.zombie)
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrDuplicateEnv is returned by Start when WithStrictEnv is applied and an environment variable is set twice.
+var ErrDuplicateEnv = errors.New("duplicate environment variable")
+
+// redacted replaces values of secret environment variables.
+const redacted = "***"
+
+// SetSecretEnv sets the environment variable like AddEnv, but marks it as secret. The value is passed to the
+// process, but it is redacted in String and EnvDiff.
+func (p *Process) SetSecretEnv(name string, value string) {
+	if p.secrets == nil {
+		p.secrets = map[string]bool{}
+	}
+	p.secrets[name] = true
+	p.AddEnv(name, value)
+}
+
+// EnvDiff returns the environment variables set on the process that are absent or have a different value in the
+// environment of the current process, in "NAME=value" form. Values of secrets are redacted.
+func (p *Process) EnvDiff() []string {
+	var diff []string
+	for _, kv := range p.envList() {
+		name, value, _ := strings.Cut(kv, "=")
+		if parent, ok := os.LookupEnv(name); ok && parent == value {
+			continue
+		}
+		diff = append(diff, p.redactEnv(kv))
+	}
+	return diff
+}
+
+// WithEnvFilter selects the variables the process inherits from the environment of the current process, e.g. to
+// keep CI tokens or GOFLAGS from leaking to the child. The variables for which keep returns false are dropped. The
+// variables set with AddEnv are not filtered.
+func WithEnvFilter(keep func(key string) bool) Option {
+	return func(p *Process) error {
+		p.envFilter = keep
+		return nil
+	}
+}
+
+// environ returns the environment of the command: the inherited variables accepted by the filter followed by the
+// variables set on the process. It returns nil, i.e. inherit all, if there is nothing to change.
+func (p *process) environ() []string {
+	if len(p.env) == 0 && p.envFilter == nil {
+		return nil
+	}
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if p.envFilter == nil || p.envFilter(name) {
+			env = append(env, kv)
+		}
+	}
+	return append(env, p.envList()...)
+}
+
+// envList returns the variables set on the process in "NAME=value" form, in the order they were first set.
+func (p *process) envList() []string {
+	var env []string
+	for _, name := range p.envOrder {
+		env = append(env, name+"="+p.env[name])
+	}
+	return env
+}
+
+// WithStrictEnv makes setting an environment variable that is already set an error, e.g. to catch two helpers that
+// configure the same variable. AddEnv does not return errors, so the first duplicate is reported by Start, wrapping
+// ErrDuplicateEnv. It applies to the variables set after the option.
+func WithStrictEnv() Option {
+	return func(p *Process) error {
+		p.strictEnv = true
+		return nil
+	}
+}
+
+// redactEnv replaces the value of "NAME=value" pair if the variable is secret.
+func (p *process) redactEnv(kv string) string {
+	name, _, _ := strings.Cut(kv, "=")
+	if p.secrets[name] {
+		return name + "=" + redacted
+	}
+	return kv
+}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadEnvFile reads KEY=VALUE lines from a .env file and sets them with AddEnv. Empty lines and lines starting with
+// # are skipped, an optional "export " prefix is allowed. Values can be single quoted (taken literally) or double
+// quoted (\n, \t, \" and \\ escapes are supported), unquoted values end at " #" comment. Nothing is set if the
+// file has a malformed line.
+func (p *Process) LoadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var vars [][2]string
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, err := parseEnvLine(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		vars = append(vars, [2]string{name, value})
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	for _, v := range vars {
+		p.AddEnv(v[0], v[1])
+	}
+	return nil
+}
+
+// parseEnvLine parses non-empty, non-comment line of .env file.
+func parseEnvLine(line string) (string, string, error) {
+	line = strings.TrimPrefix(line, "export ")
+	name, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", "", fmt.Errorf("missing '=' in %q", line)
+	}
+	name = strings.TrimSpace(name)
+	if !envNameRe.MatchString(name) {
+		return "", "", fmt.Errorf("invalid variable name %q", name)
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", "", fmt.Errorf("unterminated single quote in %q", line)
+		}
+		return name, value[1 : len(value)-1], nil
+	case strings.HasPrefix(value, `"`):
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return "", "", fmt.Errorf("unterminated double quote in %q", line)
+		}
+		r := strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+		return name, r.Replace(value[1 : len(value)-1]), nil
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return name, value, nil
+}
@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretEnvIsRedacted(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo token is $API_TOKEN")
+	require.NoError(t, err)
+	p.AddEnv("MODE", "test")
+	p.SetSecretEnv("API_TOKEN", "s3cr3t-value")
+
+	require.Equal(t, `MODE=test API_TOKEN=*** bash -c 'echo token is $API_TOKEN'`, p.String())
+	require.Equal(t, []string{"MODE=test", "API_TOKEN=***"}, p.EnvDiff())
+	require.NotContains(t, p.String(), "s3cr3t-value")
+	require.NotContains(t, strings.Join(p.EnvDiff(), " "), "s3cr3t-value")
+
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	wg.Wait()
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "token is s3cr3t-value"))
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", `echo "$APP_MODE|$APP_PORT|$GREETING|$RAW"`)
+	require.NoError(t, err)
+	require.NoError(t, p.LoadEnvFile("testdata/test.env"))
+
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	wg.Wait()
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{`test|8080|hello "world"|no $expansion here`}, lines)
+}
+
+func TestLoadEnvFileMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.env")
+	require.NoError(t, os.WriteFile(path, []byte("GOOD=1\nthis line is broken\n"), 0o644))
+	p, err := NewProcess(context.TODO(), "true")
+	require.NoError(t, err)
+	err = p.LoadEnvFile(path)
+	require.ErrorContains(t, err, "bad.env:2: missing '='")
+	require.Empty(t, p.EnvDiff())
+}
+
+func TestWithEnvFilter(t *testing.T) {
+	t.Setenv("RUNNER_CI_TOKEN", "leaked")
+	t.Setenv("RUNNER_KEPT", "kept")
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", `echo "token=$RUNNER_CI_TOKEN kept=$RUNNER_KEPT mode=$MODE" && which bash`)
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithEnvFilter(func(key string) bool {
+		return key != "RUNNER_CI_TOKEN"
+	})))
+	p.AddEnv("MODE", "test")
+	require.NoError(t, p.Run())
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	require.Equal(t, "token= kept=kept mode=test", lines[0])
+	// PATH is inherited, so the lookup works.
+	require.NotEmpty(t, lines[1])
+}
+
+func TestAddEnvInherits(t *testing.T) {
+	t.Setenv("RUNNER_PARENT", "parent")
+	p, err := NewProcess(context.TODO(), "bash", "-c", `echo "$RUNNER_PARENT $MODE"`)
+	require.NoError(t, err)
+	p.AddEnv("MODE", "test")
+	require.NoError(t, p.Run())
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"parent test"}, lines)
+}
+
+func TestAddEnvOverwrites(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", `echo "$MODE $LEVEL"`)
+	require.NoError(t, err)
+	p.AddEnv("MODE", "dev")
+	p.AddEnv("LEVEL", "debug")
+	p.AddEnv("MODE", "test")
+	require.Equal(t, `MODE=test LEVEL=debug bash -c 'echo "$MODE $LEVEL"'`, p.String())
+	require.NoError(t, p.Run())
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"test debug"}, lines)
+}
+
+func TestWithStrictEnv(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo $MODE")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithStrictEnv()))
+	p.AddEnv("MODE", "dev")
+	p.AddEnv("MODE", "test")
+	err = p.Start()
+	require.ErrorIs(t, err, ErrDuplicateEnv)
+	require.ErrorContains(t, err, "MODE")
+	require.Equal(t, StatusNotStarted, p.Status())
+}
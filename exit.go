@@ -0,0 +1,344 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"time"
+)
+
+// ExitReason classifies how the process exited.
+type ExitReason string
+
+const (
+	// ReasonNone means the process has not exited yet.
+	ReasonNone ExitReason = ""
+	// ReasonSuccess means the process exited with code 0.
+	ReasonSuccess ExitReason = "success"
+	// ReasonFailure means the process exited with non-zero code.
+	ReasonFailure ExitReason = "failure"
+	// ReasonSignaled means the process was terminated by a signal it did not get from this package.
+	ReasonSignaled ExitReason = "signaled"
+	// ReasonKilled means the process was terminated by Kill or KillWith.
+	ReasonKilled ExitReason = "killed"
+	// ReasonCanceled means the process was terminated because its context was done.
+	ReasonCanceled ExitReason = "canceled"
+)
+
+// summaryTailLines is the number of output lines included in RunSummary.
+const summaryTailLines = 10
+
+// RunSummary describes a single run of the process.
+type RunSummary struct {
+	Name      string
+	Args      []string
+	StartedAt time.Time
+	ExitedAt  time.Time
+	// ExitCode is -1 if the process has not exited or was terminated by a signal.
+	ExitCode int
+	Reason   ExitReason
+	// Tail is the last lines of the combined output.
+	Tail []string
+}
+
+// Duration returns how long the process was running, or zero if it has not exited yet.
+func (s RunSummary) Duration() time.Duration {
+	if s.StartedAt.IsZero() || s.ExitedAt.IsZero() {
+		return 0
+	}
+	return s.ExitedAt.Sub(s.StartedAt)
+}
+
+// MarshalJSON encodes the summary with snake case field names and the duration in milliseconds. Times that are
+// not set are omitted.
+func (s RunSummary) MarshalJSON() ([]byte, error) {
+	type summary struct {
+		Name       string     `json:"name"`
+		Args       []string   `json:"args"`
+		StartedAt  *time.Time `json:"started_at,omitempty"`
+		ExitedAt   *time.Time `json:"exited_at,omitempty"`
+		DurationMs int64      `json:"duration_ms"`
+		ExitCode   int        `json:"exit_code"`
+		Reason     ExitReason `json:"reason"`
+		Tail       []string   `json:"tail"`
+	}
+	v := summary{
+		Name:       s.Name,
+		Args:       s.Args,
+		DurationMs: s.Duration().Milliseconds(),
+		ExitCode:   s.ExitCode,
+		Reason:     s.Reason,
+		Tail:       s.Tail,
+	}
+	if !s.StartedAt.IsZero() {
+		v.StartedAt = &s.StartedAt
+	}
+	if !s.ExitedAt.IsZero() {
+		v.ExitedAt = &s.ExitedAt
+	}
+	return json.Marshal(v)
+}
+
+// Summary returns the summary of the process run. It can be called at any time, fields that are not known yet
+// are left empty.
+func (p *Process) Summary() RunSummary {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return RunSummary{
+		Name:      p.shortName,
+		Args:      append([]string(nil), p.cmd.Args[1:]...),
+		StartedAt: p.startedAt,
+		ExitedAt:  p.exitedAt,
+		ExitCode:  p.exitCode,
+		Reason:    p.reason,
+		Tail:      p.combined.Tail(summaryTailLines),
+	}
+}
+
+// ErrProcessExited is returned when the process has already exited, e.g. by Signal, and it is the cause of the
+// context returned by ExitContext. If the process was killed with a cause, see
+// KillWithCause, or because its context was done, the cause wraps that reason too.
+var ErrProcessExited = errors.New("process exited")
+
+// ExitContext returns a context that is cancelled when the process exits for any reason, e.g. to stop the work that
+// depends on the process. Its cause, see context.Cause, wraps ErrProcessExited and describes the exit. The context
+// of a restarted process is a new one.
+func (p *Process) ExitContext() context.Context {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.exitCtx
+}
+
+// exitCause returns the cause of the exit context. It should be called with p.m locked.
+func (p *process) exitCause() error {
+	switch {
+	case p.killCause != nil:
+		return fmt.Errorf("%w: %s was killed: %w", ErrProcessExited, p.shortName, p.killCause)
+	case p.reason == ReasonCanceled:
+		return fmt.Errorf("%w: %s was canceled: %w", ErrProcessExited, p.shortName, context.Cause(p.ctx))
+	}
+	return fmt.Errorf("%w: %s exited with code %d (%s)", ErrProcessExited, p.shortName, p.exitCode, p.reason)
+}
+
+// exitStderrLimit is the maximum number of the last stderr bytes kept in ExitError.
+const exitStderrLimit = 4096
+
+// ExitError is the exit error of the process that exited with non-zero code or was terminated by a signal. In
+// addition to exec.ExitError it carries the tail of stderr, so the failure is self-describing.
+type ExitError struct {
+	*exec.ExitError
+	// Stderr is up to the last 4 KiB of the process stderr.
+	Stderr []byte
+}
+
+func (e *ExitError) Error() string {
+	if len(e.Stderr) == 0 {
+		return e.ExitError.Error()
+	}
+	return fmt.Sprintf("%s, stderr:\n%s", e.ExitError.Error(), bytes.TrimRight(e.Stderr, "\n"))
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.ExitError
+}
+
+// withStderr wraps exec.ExitError into ExitError with the tail of stderr, other errors are returned as is.
+func (p *process) withStderr(err error) error {
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return err
+	}
+	stderr := p.stderr.Snapshot()
+	return &ExitError{
+		ExitError: ee,
+		Stderr:    stderr[max(len(stderr)-exitStderrLimit, 0):],
+	}
+}
+
+// Run starts the process and waits for it to exit, it returns *ExitError if the process fails.
+func (p *Process) Run() error {
+	if err := p.Start(); err != nil {
+		return err
+	}
+	<-p.Done()
+	return p.ExitError()
+}
+
+// Output runs the process and returns its stdout. It returns *ExitError if the process fails.
+func (p *Process) Output() ([]byte, error) {
+	err := p.Run()
+	stdout, _, _ := p.outputs()
+	return stdout.Snapshot(), err
+}
+
+// ExitStatus describes how the process exited, see Wait.
+type ExitStatus struct {
+	// Code is the exit code, or -1 if the process was terminated by a signal.
+	Code   int
+	Reason ExitReason
+	// Signal is the signal that terminated the process, or nil. It is known only on Unix.
+	Signal os.Signal
+}
+
+// Wait blocks until the process exits and returns its exit status and ExitError, i.e. *ExitError wrapping
+// *exec.ExitError if the process failed, so the caller decides how to react. It returns the context error if ctx is
+// done first.
+func (p *Process) Wait(ctx context.Context) (ExitStatus, error) {
+	select {
+	case <-p.Done():
+	case <-ctx.Done():
+		return ExitStatus{Code: -1}, ctx.Err()
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	return ExitStatus{
+		Code:   p.exitCode,
+		Reason: p.reason,
+		Signal: exitSignal(p.cmd.ProcessState),
+	}, p.waitErr
+}
+
+// Done returns a channel that is closed when the process exits. A restarted process has a new channel.
+func (p *Process) Done() <-chan struct{} {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.done
+}
+
+// WaitForExit blocks until the process exits and returns ExitError, or returns the context error if ctx is done
+// first.
+func (p *Process) WaitForExit(ctx context.Context) error {
+	select {
+	case <-p.Done():
+		return p.ExitError()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ExitError returns the result of cmd.Wait, i.e. nil if the process exited successfully or has not exited yet. A
+// non-zero exit is reported as *ExitError.
+func (p *Process) ExitError() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.waitErr
+}
+
+// ExitCode returns the exit code of the process, or -1 if it has not exited yet or was terminated by a signal.
+func (p *Process) ExitCode() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.exitCode
+}
+
+// Exited returns true if the process has exited, on its own or killed.
+func (p *Process) Exited() bool {
+	select {
+	case <-p.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// ExitState is a copy of os.ProcessState of the exited process.
+type ExitState struct {
+	Pid int
+	// ExitCode is the exit code, or -1 if the process was terminated by a signal.
+	ExitCode int
+	// Success is true if the process exited with code 0, it does not consider SetSuccessCodes.
+	Success bool
+	// Signal is the signal that terminated the process, or nil. It is known only on Unix.
+	Signal     os.Signal
+	UserTime   time.Duration
+	SystemTime time.Duration
+}
+
+// State returns the state of the exited process, or false if the process has not exited yet.
+func (p *Process) State() (ExitState, bool) {
+	if !p.Exited() {
+		return ExitState{}, false
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	ps := p.cmd.ProcessState
+	if ps == nil {
+		return ExitState{}, false
+	}
+	return ExitState{
+		Pid:        ps.Pid(),
+		ExitCode:   ps.ExitCode(),
+		Success:    ps.Success(),
+		Signal:     exitSignal(ps),
+		UserTime:   ps.UserTime(),
+		SystemTime: ps.SystemTime(),
+	}, true
+}
+
+// ExitReason returns how the process exited, or ReasonNone if it is still running.
+func (p *Process) ExitReason() ExitReason {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.reason
+}
+
+// classifyExit returns the exit code and reason for the result of cmd.Wait. It should be called with p.m locked.
+func (p *process) classifyExit(err error) (int, ExitReason) {
+	code := -1
+	if p.cmd.ProcessState != nil {
+		code = p.cmd.ProcessState.ExitCode()
+	}
+	switch {
+	case p.stopped && !p.killed:
+		return code, ReasonCanceled
+	case err == nil, errors.Is(err, exec.ErrWaitDelay) && code == 0:
+		return code, ReasonSuccess
+	case p.killed:
+		return code, ReasonKilled
+	case p.ctx.Err() != nil:
+		return code, ReasonCanceled
+	case code == -1:
+		return code, ReasonSignaled
+	case slices.Contains(p.successCodes, code):
+		return code, ReasonSuccess
+	}
+	return code, ReasonFailure
+}
+
+// SetSuccessCodes declares non-zero exit codes that are successful, e.g. 1 of grep that found nothing. The process
+// that exits with such code has ReasonSuccess and no exit error. Zero is always successful. It should be called
+// before the process exits.
+func (p *Process) SetSuccessCodes(codes ...int) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.successCodes = codes
+}
+
+// Succeeded returns true if the process exited successfully, i.e. with zero code or one of the codes declared with
+// SetSuccessCodes.
+func (p *Process) Succeeded() bool {
+	return p.ExitReason() == ReasonSuccess
+}
+
+// SetExitSummary enables a summary line printed with the process output when the process exits, e.g.
+// "[exited code=0 reason=success dur=1.203s]". It is off by default. It should be called before the process exits.
+func (p *Process) SetExitSummary(enabled bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.exitSummary = enabled
+}
+
+// printExitSummary prints the exit summary line through the printer of the process.
+func (p *process) printExitSummary() {
+	p.m.Lock()
+	line := fmt.Sprintf("[exited code=%d reason=%s dur=%s]", p.exitCode, p.reason,
+		p.exitedAt.Sub(p.startedAt).Round(time.Millisecond))
+	p.m.Unlock()
+	_, _ = p.printer.Write([]byte(line))
+	_ = p.printer.Flush()
+}
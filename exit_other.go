@@ -0,0 +1,10 @@
+//go:build !unix
+
+package runner
+
+import "os"
+
+// exitSignal is not supported on this platform.
+func exitSignal(*os.ProcessState) os.Signal {
+	return nil
+}
@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryJSON(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo one && echo two 1>&2 && exit 3")
+	require.NoError(t, err)
+	require.Equal(t, ReasonNone, p.Summary().Reason)
+
+	require.NoError(t, p.Start())
+	<-p.done
+
+	data, err := json.Marshal(p.Summary())
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, "bash", decoded["name"])
+	require.Equal(t, []any{"-c", "echo one && echo two 1>&2 && exit 3"}, decoded["args"])
+	require.Equal(t, float64(3), decoded["exit_code"])
+	require.Equal(t, "failure", decoded["reason"])
+	// stdout and stderr are separate pipes, the order of their lines in the combined output is not guaranteed.
+	require.ElementsMatch(t, []any{"one", "two"}, decoded["tail"])
+	require.Contains(t, decoded, "started_at")
+	require.Contains(t, decoded, "exited_at")
+	require.Contains(t, decoded, "duration_ms")
+}
+
+func TestExitErrorCarriesStderr(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo result && echo 'fatal: config not found' 1>&2 && exit 2")
+	require.NoError(t, err)
+	out, err := p.Output()
+	require.Equal(t, "result\n", string(out))
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 2, exitErr.ExitCode())
+	require.Equal(t, "fatal: config not found\n", string(exitErr.Stderr))
+	require.ErrorContains(t, err, "exit status 2, stderr:\nfatal: config not found")
+	var execErr *exec.ExitError
+	require.ErrorAs(t, err, &execErr)
+
+	// Only the tail of a large stderr is kept.
+	p, err = NewProcess(context.TODO(), "bash", "-c", "head -c 10000 /dev/zero | tr '\\0' x 1>&2; echo -n end 1>&2; exit 1")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	err = p.WaitForExit(context.TODO())
+	require.ErrorAs(t, err, &exitErr)
+	require.Len(t, exitErr.Stderr, exitStderrLimit)
+	require.True(t, bytes.HasSuffix(exitErr.Stderr, []byte("xend")))
+
+	p, err = NewProcess(context.TODO(), "true")
+	require.NoError(t, err)
+	require.NoError(t, p.Run())
+}
+
+func TestSetSuccessCodes(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "grep", "needle")
+	require.NoError(t, err)
+	p.cmd.Stdin = strings.NewReader("haystack\n")
+	p.SetSuccessCodes(1)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForExit(ctx))
+	require.Equal(t, 1, p.ExitCode())
+	require.True(t, p.Succeeded())
+
+	p, err = NewProcess(ctx, "grep", "needle")
+	require.NoError(t, err)
+	p.cmd.Stdin = strings.NewReader("haystack\n")
+	require.NoError(t, p.Start())
+	require.Error(t, p.WaitForExit(ctx))
+	require.False(t, p.Succeeded())
+	require.Equal(t, ReasonFailure, p.ExitReason())
+}
+
+func TestExitSummary(t *testing.T) {
+	var text bytes.Buffer
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo hello && exit 2")
+	require.NoError(t, err)
+	p.SetOutput(&text)
+	p.SetExitSummary(true)
+	require.Error(t, p.Run())
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	last := lines[len(lines)-1]
+	require.True(t, strings.HasPrefix(last, "bash            | [exited code=2 reason=failure dur="), last)
+	require.Contains(t, text.String(), "| hello\n")
+
+	// The summary is opt-in.
+	text.Reset()
+	p, err = NewProcess(context.TODO(), "bash", "-c", "echo hello")
+	require.NoError(t, err)
+	p.SetOutput(&text)
+	require.NoError(t, p.Run())
+	require.NotContains(t, text.String(), "[exited")
+}
+
+func TestExitContext(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "sleep 0.2 && exit 4")
+	require.NoError(t, err)
+	ctx := p.ExitContext()
+	require.NoError(t, ctx.Err())
+	require.NoError(t, p.Start())
+
+	// Work derived from the exit context stops when the process exits.
+	work, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	<-work.Done()
+	require.ErrorIs(t, work.Err(), context.Canceled)
+	cause := context.Cause(work)
+	require.ErrorIs(t, cause, ErrProcessExited)
+	require.EqualError(t, cause, "process exited: bash exited with code 4 (failure)")
+}
+
+func TestExitContextCause(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.KillWithCause(os.Kill, errors.New("deadline of the test case")))
+	<-p.ExitContext().Done()
+	require.EqualError(t, context.Cause(p.ExitContext()), "process exited: sleep was killed: deadline of the test case")
+
+	ctx, cancel := context.WithCancelCause(context.TODO())
+	p, err = NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	cancel(errors.New("suite teardown"))
+	<-p.ExitContext().Done()
+	require.EqualError(t, context.Cause(p.ExitContext()), "process exited: sleep was canceled: suite teardown")
+}
+
+func TestExitState(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "for i in $(seq 1 20000); do :; done; exit 5")
+	require.NoError(t, err)
+	require.False(t, p.Exited())
+	_, ok := p.State()
+	require.False(t, ok)
+	require.Error(t, p.Run())
+
+	require.True(t, p.Exited())
+	require.Equal(t, 5, p.ExitCode())
+	state, ok := p.State()
+	require.True(t, ok)
+	require.Equal(t, p.cmd.Process.Pid, state.Pid)
+	require.Equal(t, 5, state.ExitCode)
+	require.False(t, state.Success)
+	require.Nil(t, state.Signal)
+	require.Positive(t, state.UserTime+state.SystemTime)
+}
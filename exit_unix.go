@@ -0,0 +1,19 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// exitSignal returns the signal that terminated the process, or nil.
+func exitSignal(state *os.ProcessState) os.Signal {
+	if state == nil {
+		return nil
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return ws.Signal()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// lineFanout reads the output once and shares the lines with its scanners, so n concurrent waits cost one read
+// pass instead of n.
+type lineFanout struct {
+	source *AccumulatedOutput
+	once   sync.Once
+
+	m     sync.Mutex
+	lines []string
+	// closed is set when the output is read to the end, err is the error that stopped the reading, if any.
+	closed bool
+	err    error
+	// changed is closed and replaced when lines are added or the reading stops.
+	changed chan struct{}
+}
+
+// fanoutScanner is a scanner of the lines read by lineFanout.
+type fanoutScanner struct {
+	f *lineFanout
+}
+
+// Scanners returns n scanners of the output backed by a single read of the buffer. A scanner behaves like the
+// output itself, i.e. every WaitForKeyword scans from the beginning, but the waits of all scanners, e.g. for
+// different keywords in different goroutines, share the read. The lines are kept in memory until the scanners are
+// garbage collected. The read starts with the first wait and runs until the output is closed.
+func (s *AccumulatedOutput) Scanners(n int) []OutputScanner {
+	f := &lineFanout{
+		source:  s,
+		changed: make(chan struct{}),
+	}
+	scanners := make([]OutputScanner, n)
+	for i := range scanners {
+		scanners[i] = &fanoutScanner{f: f}
+	}
+	return scanners
+}
+
+// run reads the lines of the output until it is closed. CancelScans aborts the waits, but not the shared read.
+func (f *lineFanout) run() {
+	err := f.source.scanLines(context.Background(), func(line string) bool {
+		f.m.Lock()
+		f.lines = append(f.lines, line)
+		f.notify()
+		f.m.Unlock()
+		return true
+	})
+	f.m.Lock()
+	f.closed = true
+	if !errors.Is(err, io.EOF) {
+		f.err = err
+	}
+	f.notify()
+	f.m.Unlock()
+}
+
+// notify wakes up the pending waits. It should be called with f.m locked.
+func (f *lineFanout) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// WaitForKeyword scans the shared lines for given substr. It exits with nil when substr is found and with
+// KeywordNotFound when the output is closed and substr is not found.
+func (s *fanoutScanner) WaitForKeyword(ctx context.Context, substr string) error {
+	ctx, cancel := s.f.source.withScanCancel(ctx)
+	defer cancel()
+	s.f.once.Do(func() {
+		go s.f.run()
+	})
+	next := 0
+	for {
+		s.f.m.Lock()
+		for ; next < len(s.f.lines); next++ {
+			if strings.Contains(s.f.lines[next], substr) {
+				s.f.m.Unlock()
+				return nil
+			}
+		}
+		closed, err, changed := s.f.closed, s.f.err, s.f.changed
+		s.f.m.Unlock()
+		if closed {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%w '%s' in output", KeywordNotFound, substr)
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return scanError(ctx, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanners(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	scanners := out.Scanners(3)
+	require.Len(t, scanners, 3)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	markers := []string{"db ready", "cache ready", "api ready"}
+	errs := make([]error, len(markers))
+	var wg sync.WaitGroup
+	for i, marker := range markers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = scanners[i].WaitForKeyword(ctx, marker)
+		}()
+	}
+	for i, marker := range markers {
+		fmt.Fprintf(out, "line %d\n%s\n", i, marker)
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	// The waits share a single reader of the buffer, which is released when the output is closed.
+	require.LessOrEqual(t, openReaders(out), 1)
+
+	require.NoError(t, out.Close())
+	require.NoError(t, scanners[0].WaitForKeyword(ctx, "api ready"))
+	err := scanners[1].WaitForKeyword(ctx, "missing")
+	require.ErrorIs(t, err, KeywordNotFound)
+	require.EqualError(t, err, "failed to find keyword 'missing' in output")
+	require.Zero(t, openReaders(out))
+}
+
+func TestScannersContextDone(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	defer out.Close()
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, out.Scanners(1)[0].WaitForKeyword(ctx, "never"), context.DeadlineExceeded)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- out.Scanners(1)[0].WaitForKeyword(context.TODO(), "never")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	out.CancelScans()
+	require.ErrorIs(t, <-done, ErrScannersCancelled)
+}
+
+// openReaders returns the number of open readers of the output buffer.
+func openReaders(out *AccumulatedOutput) int {
+	b := out.buf.(*multiReaderBuffer)
+	b.cv.L.Lock()
+	defer b.cv.L.Unlock()
+	return len(b.readers)
+}
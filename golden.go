@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"flag"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// GoldenOption configures AssertGolden.
+type GoldenOption func(*goldenConfig)
+
+type goldenReplacement struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+type goldenConfig struct {
+	replacements []goldenReplacement
+	update       bool
+}
+
+// GoldenReplace normalizes the output before comparison by replacing all matches of re with repl. It is useful
+// to strip timestamps, ports, temporary paths and other values that differ between runs.
+func GoldenReplace(re *regexp.Regexp, repl string) GoldenOption {
+	return func(c *goldenConfig) {
+		c.replacements = append(c.replacements, goldenReplacement{re: re, repl: repl})
+	}
+}
+
+// GoldenUpdate forces AssertGolden to overwrite the golden file instead of comparing with it.
+func GoldenUpdate(update bool) GoldenOption {
+	return func(c *goldenConfig) {
+		c.update = update
+	}
+}
+
+// updateFlag reports if the test binary was started with -update. The package does not define the flag itself,
+// to avoid conflicts with tests that already have one, so it is looked up at runtime.
+func updateFlag() bool {
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	v, _ := strconv.ParseBool(f.Value.String())
+	return v
+}
+
+// AssertGolden compares the output accumulated so far with the content of the golden file at path. The output is
+// normalized by GoldenReplace options first. When the test binary runs with -update flag (defined by the caller's
+// test package) or GoldenUpdate(true) is passed, the golden file is overwritten with the normalized output.
+func (s *AccumulatedOutput) AssertGolden(tb testing.TB, path string, opts ...GoldenOption) {
+	tb.Helper()
+	c := goldenConfig{update: updateFlag()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	got := s.Snapshot()
+	for _, r := range c.replacements {
+		got = r.re.ReplaceAll(got, []byte(r.repl))
+	}
+	if c.update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("failed to read golden file %s: %v", path, err)
+	}
+	if string(want) != string(got) {
+		tb.Errorf("output does not match golden file %s\n--- want:\n%s\n--- got:\n%s", path, want, got)
+	}
+}
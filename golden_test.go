@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// AssertGolden looks the flag up at runtime, so test packages only need to define it.
+var _ = flag.Bool("update", false, "update golden files")
+
+func newServerOutput(t *testing.T) *AccumulatedOutput {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("server started at 2024-05-01T10:11:12Z\nlistening on port 51234\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	return out
+}
+
+var serverNormalizers = []GoldenOption{
+	GoldenReplace(regexp.MustCompile(`\d{4}-\d{2}-\d{2}T[\d:]+Z`), "<TIME>"),
+	GoldenReplace(regexp.MustCompile(`port \d+`), "port <PORT>"),
+}
+
+func TestAssertGolden(t *testing.T) {
+	newServerOutput(t).AssertGolden(t, "testdata/server.golden", serverNormalizers...)
+}
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.golden")
+	out := newServerOutput(t)
+	out.AssertGolden(t, path, append(serverNormalizers, GoldenUpdate(true))...)
+
+	updated, err := os.ReadFile(path)
+	require.NoError(t, err)
+	expected, err := os.ReadFile("testdata/server.golden")
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(updated))
+
+	// Compare mode passes against the freshly written file.
+	out.AssertGolden(t, path, serverNormalizers...)
+}
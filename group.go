@@ -0,0 +1,277 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrQuorumNotReached is returned by WaitForQuorum when too many processes failed to become ready.
+var ErrQuorumNotReached = errors.New("quorum not reached")
+
+// ProcessGroup is a collection of named processes that are managed together.
+type ProcessGroup struct {
+	m       sync.Mutex
+	names   []string
+	procs   map[string]*Process
+	started []string
+	// running tracks the processes started with StartAsync by StartAll.
+	running sync.WaitGroup
+	deps    map[string][]string
+	ready   map[string]func(ctx context.Context) error
+}
+
+// NewProcessGroup returns an empty ProcessGroup.
+func NewProcessGroup() *ProcessGroup {
+	return &ProcessGroup{
+		procs: map[string]*Process{},
+	}
+}
+
+// Add adds the process to the group under a given name. Names must be unique.
+func (g *ProcessGroup) Add(name string, p *Process) error {
+	g.m.Lock()
+	defer g.m.Unlock()
+	if _, ok := g.procs[name]; ok {
+		return fmt.Errorf("process %s is already in the group", name)
+	}
+	g.names = append(g.names, name)
+	g.procs[name] = p
+	return nil
+}
+
+// Get returns the process with a given name or nil.
+func (g *ProcessGroup) Get(name string) *Process {
+	g.m.Lock()
+	defer g.m.Unlock()
+	return g.procs[name]
+}
+
+// Names returns the names of the processes in the order they were added.
+func (g *ProcessGroup) Names() []string {
+	g.m.Lock()
+	defer g.m.Unlock()
+	return append([]string(nil), g.names...)
+}
+
+// DependsOn declares that the process with a given name starts only after the deps are ready, see SetReadiness.
+// The deps can be added to the group later, they are resolved by StartAll.
+func (g *ProcessGroup) DependsOn(name string, deps ...string) error {
+	g.m.Lock()
+	defer g.m.Unlock()
+	if _, ok := g.procs[name]; !ok {
+		return fmt.Errorf("process %s is not in the group", name)
+	}
+	if g.deps == nil {
+		g.deps = map[string][]string{}
+	}
+	g.deps[name] = append(g.deps[name], deps...)
+	return nil
+}
+
+// SetReadiness sets the check that tells when the started process is ready, e.g. Probe of PortProbe. The
+// check is expected to block until the process is ready or ctx is done. Without the check the process is ready as
+// soon as it is started.
+func (g *ProcessGroup) SetReadiness(name string, ready func(ctx context.Context) error) error {
+	g.m.Lock()
+	defer g.m.Unlock()
+	if _, ok := g.procs[name]; !ok {
+		return fmt.Errorf("process %s is not in the group", name)
+	}
+	if g.ready == nil {
+		g.ready = map[string]func(ctx context.Context) error{}
+	}
+	g.ready[name] = ready
+	return nil
+}
+
+// errStartAborted cancels the startup of the group after a failure.
+var errStartAborted = errors.New("start aborted")
+
+// StartAll starts the processes of the group, each one after all its dependencies are ready, see DependsOn. The
+// independent processes are started in parallel. It returns an error wrapping ErrDependencyCycle, without starting
+// anything, if the dependencies form a cycle. If a process fails to start or to become ready, or ctx is done, it
+// does not start the rest of the processes, stops the processes started so far, see StopAll, and returns the errors.
+// The processes are started like with StartAsync, so an unexpected exit is reported to the failure handler of the
+// process, see SetFailureHandler.
+func (g *ProcessGroup) StartAll(ctx context.Context) error {
+	if err := g.checkDeps(); err != nil {
+		return err
+	}
+	names := g.Names()
+	ready := map[string]chan struct{}{}
+	for _, name := range names {
+		ready[name] = make(chan struct{})
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	results := make(chan error, len(names))
+	for _, name := range names {
+		go func() {
+			err := g.startWhenReady(ctx, name, ready)
+			if err == nil {
+				close(ready[name])
+			}
+			results <- err
+		}()
+	}
+	var errs []error
+	for range names {
+		err := <-results
+		if err == nil || len(errs) > 0 && (errors.Is(err, errStartAborted) || errors.Is(err, context.Canceled)) {
+			continue
+		}
+		errs = append(errs, err)
+		cancel(errStartAborted)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(append(errs, g.StopAll(context.WithoutCancel(ctx)))...)
+}
+
+// startWhenReady waits for the dependencies of the process to be ready, starts it and waits for it to be ready.
+func (g *ProcessGroup) startWhenReady(ctx context.Context, name string, ready map[string]chan struct{}) error {
+	g.m.Lock()
+	deps := g.deps[name]
+	check := g.ready[name]
+	p := g.procs[name]
+	g.m.Unlock()
+	for _, dep := range deps {
+		select {
+		case <-ready[dep]:
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", name, context.Cause(ctx))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, context.Cause(ctx))
+	}
+	if err := p.StartAsync(&g.running); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	g.m.Lock()
+	g.started = append(g.started, name)
+	g.m.Unlock()
+	if check == nil {
+		return nil
+	}
+	if err := check(ctx); err != nil {
+		return fmt.Errorf("%s is not ready: %w", name, err)
+	}
+	return nil
+}
+
+// ErrDependencyCycle is returned by StartAll when the dependencies of the processes form a cycle.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// checkDeps checks that all the dependencies are in the group and that they do not form a cycle.
+func (g *ProcessGroup) checkDeps() error {
+	g.m.Lock()
+	defer g.m.Unlock()
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			cycle := append(path[slices.Index(path, name):], name)
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range g.deps[name] {
+			if _, ok := g.procs[dep]; !ok {
+				return fmt.Errorf("process %s depends on %s, which is not in the group", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, name := range g.names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopAll stops the processes started by StartAll with Process.Stop, in reverse start order, so a process is stopped
+// before the processes it depends on, see DependsOn. The processes that already exited are skipped. It stops all the processes
+// and returns the errors joined, each prefixed with the name of the process.
+func (g *ProcessGroup) StopAll(ctx context.Context) error {
+	g.m.Lock()
+	started := g.started
+	g.started = nil
+	g.m.Unlock()
+	var errs []error
+	for _, name := range slices.Backward(started) {
+		p := g.Get(name)
+		if p.Exited() {
+			continue
+		}
+		if err := p.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	g.running.Wait()
+	return errors.Join(errs...)
+}
+
+// WaitForQuorum waits until at least quorum processes of the group print the marker, in stdout or stderr. It
+// does not wait for the rest of the processes. It exits with ErrQuorumNotReached as soon as it is clear that the
+// quorum cannot be reached, i.e. output of too many processes was closed without the marker.
+func (g *ProcessGroup) WaitForQuorum(ctx context.Context, marker string, quorum int) error {
+	names := g.Names()
+	if quorum > len(names) {
+		return fmt.Errorf("%w: quorum %d is larger than group size %d", ErrQuorumNotReached, quorum, len(names))
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(names))
+	for _, name := range names {
+		p := g.Get(name)
+		go func() {
+			results <- result{name: name, err: p.CombinedScanner().WaitForKeyword(ctx, marker)}
+		}()
+	}
+	ready := 0
+	var failures []error
+	for range names {
+		r := <-results
+		if r.err == nil {
+			ready++
+			if ready >= quorum {
+				return nil
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failures = append(failures, fmt.Errorf("%s: %w", r.name, r.err))
+		if len(names)-len(failures) < quorum {
+			break
+		}
+	}
+	return fmt.Errorf("%w: %d of %d ready: %w", ErrQuorumNotReached, ready, quorum, errors.Join(failures...))
+}
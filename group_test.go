@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForQuorum(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	var wg sync.WaitGroup
+	scripts := []string{
+		"echo node ready && sleep 5",
+		"sleep 0.2 && echo node ready && sleep 5",
+		"sleep 5",
+	}
+	for i, script := range scripts {
+		p, err := NewProcess(ctx, "bash", "-c", script)
+		require.NoError(t, err)
+		require.NoError(t, g.Add(fmt.Sprintf("node%d", i), p))
+		require.NoError(t, p.StartAsync(&wg))
+	}
+	defer func() {
+		for _, name := range g.Names() {
+			g.Get(name).Kill()
+		}
+		wg.Wait()
+	}()
+
+	require.NoError(t, g.WaitForQuorum(ctx, "node ready", 2))
+
+	short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, g.WaitForQuorum(short, "node ready", 3), context.DeadlineExceeded)
+	require.ErrorIs(t, g.WaitForQuorum(ctx, "node ready", 4), ErrQuorumNotReached)
+}
+
+func TestWaitForQuorumFailsFast(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	var wg sync.WaitGroup
+	for i, script := range []string{"echo node ready", "echo crashed", "echo crashed"} {
+		p, err := NewProcess(ctx, "bash", "-c", script)
+		require.NoError(t, err)
+		require.NoError(t, g.Add(fmt.Sprintf("node%d", i), p))
+		require.NoError(t, p.StartAsync(&wg))
+	}
+	wg.Wait()
+	require.ErrorIs(t, g.WaitForQuorum(ctx, "node ready", 2), ErrQuorumNotReached)
+}
+
+func TestStartAllStopAll(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	var stopped []string
+	var m sync.Mutex
+	for _, name := range []string{"db", "api", "web"} {
+		p, err := NewProcess(ctx, "sleep", "5")
+		require.NoError(t, err)
+		p.OnExit(func() {
+			m.Lock()
+			defer m.Unlock()
+			stopped = append(stopped, name)
+		})
+		p.SetStopSequence(os.Kill, 0)
+		require.NoError(t, g.Add(name, p))
+	}
+	require.NoError(t, g.DependsOn("api", "db"))
+	require.NoError(t, g.DependsOn("web", "api"))
+	require.NoError(t, g.StartAll(ctx))
+	for _, name := range g.Names() {
+		require.Equal(t, StatusRunning, g.Get(name).Status())
+	}
+	require.NoError(t, g.StopAll(ctx))
+	require.Equal(t, []string{"web", "api", "db"}, stopped)
+	for _, name := range g.Names() {
+		require.Equal(t, ReasonKilled, g.Get(name).ExitReason())
+	}
+}
+
+func TestStartAllFailure(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	db, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("db", db))
+	missing, err := NewProcess(ctx, "no-such-binary-for-runner-test")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("api", missing))
+	require.NoError(t, g.DependsOn("api", "db"))
+
+	err = g.StartAll(ctx)
+	require.ErrorContains(t, err, "api: ")
+	// The processes started before the failure are stopped.
+	require.True(t, db.Exited())
+	require.Equal(t, ReasonKilled, db.ExitReason())
+}
+
+func TestStartAllDependencies(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	scripts := map[string]string{
+		"db":    "sleep 0.3 && echo db ready && sleep 5",
+		"cache": "echo cache ready && sleep 5",
+		"api":   "echo api ready && sleep 5",
+		"web":   "sleep 5",
+	}
+	for _, name := range []string{"web", "api", "cache", "db"} {
+		p, err := NewProcess(ctx, "bash", "-c", scripts[name])
+		require.NoError(t, err)
+		p.SetStopSequence(os.Kill, 0)
+		require.NoError(t, g.Add(name, p))
+		require.NoError(t, g.SetReadiness(name, func(ctx context.Context) error {
+			return p.CombinedScanner().WaitForKeyword(ctx, name+" ready")
+		}))
+	}
+	require.NoError(t, g.SetReadiness("web", nil))
+	require.NoError(t, g.DependsOn("api", "db", "cache"))
+	require.NoError(t, g.DependsOn("web", "api"))
+	defer g.StopAll(ctx)
+
+	require.NoError(t, g.StartAll(ctx))
+	started := func(name string) time.Time {
+		return g.Get(name).Summary().StartedAt
+	}
+	// The independent processes start right away, the rest start when their dependencies are ready.
+	require.Less(t, started("cache").Sub(started("db")).Abs(), 200*time.Millisecond)
+	require.GreaterOrEqual(t, started("api").Sub(started("db")), 300*time.Millisecond)
+	require.True(t, started("web").After(started("api")))
+}
+
+func TestStartAllDependencyErrors(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	for _, name := range []string{"a", "b", "c"} {
+		p, err := NewProcess(ctx, "sleep", "5")
+		require.NoError(t, err)
+		require.NoError(t, g.Add(name, p))
+	}
+	require.Error(t, g.DependsOn("d", "a"))
+	require.NoError(t, g.DependsOn("a", "b"))
+	require.NoError(t, g.DependsOn("b", "c"))
+	require.NoError(t, g.DependsOn("c", "a"))
+	err := g.StartAll(ctx)
+	require.ErrorIs(t, err, ErrDependencyCycle)
+	require.EqualError(t, err, "dependency cycle: a -> b -> c -> a")
+	require.Equal(t, StatusNotStarted, g.Get("a").Status())
+
+	g = NewProcessGroup()
+	p, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("a", p))
+	require.NoError(t, g.DependsOn("a", "b"))
+	require.EqualError(t, g.StartAll(ctx), "process a depends on b, which is not in the group")
+}
+
+func TestStartAllNotReady(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	db, err := NewProcess(ctx, "bash", "-c", "echo starting && exit 1")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("db", db))
+	require.NoError(t, g.SetReadiness("db", func(ctx context.Context) error {
+		return db.CombinedScanner().WaitForKeyword(ctx, "db ready")
+	}))
+	cache, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("cache", cache))
+	api, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("api", api))
+	require.NoError(t, g.DependsOn("api", "db", "cache"))
+
+	err = g.StartAll(ctx)
+	require.ErrorContains(t, err, "db is not ready: ")
+	require.NotContains(t, err.Error(), "api")
+	require.Equal(t, StatusNotStarted, api.Status())
+	require.True(t, cache.Exited())
+}
+
+func TestStartAllReportsFailures(t *testing.T) {
+	ctx := context.TODO()
+	g := NewProcessGroup()
+	failures := make(chan error, 1)
+	crashing, err := NewProcess(ctx, "bash", "-c", "sleep 0.1 && exit 3")
+	require.NoError(t, err)
+	crashing.SetFailureHandler(func(p *Process, err error) {
+		failures <- err
+	})
+	require.NoError(t, g.Add("crashing", crashing))
+	stable, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, g.Add("stable", stable))
+
+	require.NoError(t, g.StartAll(ctx))
+	select {
+	case err := <-failures:
+		require.ErrorContains(t, err, "exit status 3")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the failure is not reported")
+	}
+	require.NoError(t, g.StopAll(ctx))
+}
@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// WaitForTemplate waits for a line that is a JSON object for which the Go text/template tmpl renders "true". The
+// template is executed with the parsed object as data, e.g. `{{and (eq .level "info") (eq .event "ready")}}`.
+// Lines that are not JSON objects or fail the template execution are skipped. It exits with KeywordNotFound if
+// the output is closed without a match.
+func (s *AccumulatedOutput) WaitForTemplate(ctx context.Context, tmpl string) error {
+	t, err := template.New("match").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	return s.waitForJSON(ctx, fmt.Sprintf("template %q", tmpl), func(obj map[string]any) bool {
+		buf.Reset()
+		if err := t.Execute(&buf, obj); err != nil {
+			return false
+		}
+		return strings.TrimSpace(buf.String()) == "true"
+	})
+}
+
+// WaitForJSONField waits for a line that is a JSON object with obj[key] equal to value, e.g. a structured log line
+// with "event":"ready". The value is compared in its JSON form, so numbers of any Go type match JSON numbers. Lines
+// that are not JSON objects are skipped. It exits with KeywordNotFound if the output is closed without a match.
+func (s *AccumulatedOutput) WaitForJSONField(ctx context.Context, key string, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+	var want any
+	if err := json.Unmarshal(encoded, &want); err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+	return s.waitForJSON(ctx, fmt.Sprintf("%q=%s", key, encoded), func(obj map[string]any) bool {
+		got, ok := obj[key]
+		return ok && reflect.DeepEqual(got, want)
+	})
+}
+
+// WaitForNumericField waits for a line that is a JSON object with a number in obj[key] that satisfies pred, e.g.
+// `func(v float64) bool { return v >= 3 }` for a converging peer count. Lines that are not JSON objects or have no
+// number in the field are skipped. It exits with KeywordNotFound if the output is closed without a match.
+func (s *AccumulatedOutput) WaitForNumericField(ctx context.Context, key string, pred func(float64) bool) error {
+	return s.waitForJSON(ctx, fmt.Sprintf("numeric %q satisfying the predicate", key), func(obj map[string]any) bool {
+		v, ok := obj[key].(float64)
+		return ok && pred(v)
+	})
+}
+
+// WaitForJSONSchema waits for a line that is a JSON object valid against the JSON schema, e.g. to enforce the
+// contract of structured logs. Lines that are not JSON objects are skipped. It returns an error if the schema is
+// invalid, and exits with KeywordNotFound if the output is closed without a match.
+func (s *AccumulatedOutput) WaitForJSONSchema(ctx context.Context, schema []byte) error {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", bytes.NewReader(schema)); err != nil {
+		return fmt.Errorf("failed to load schema: %w", err)
+	}
+	sch, err := compiler.Compile("schema.json")
+	if err != nil {
+		return fmt.Errorf("failed to compile schema: %w", err)
+	}
+	return s.waitForJSON(ctx, "line valid against schema", func(obj map[string]any) bool {
+		return sch.Validate(obj) == nil
+	})
+}
+
+// waitForJSON waits for a line that is a JSON object and satisfies match. Other lines are skipped. The desc is used
+// in the error message when the output is closed without a match.
+func (s *AccumulatedOutput) waitForJSON(ctx context.Context, desc string, match func(obj map[string]any) bool) error {
+	found := false
+	err := s.eachLine(ctx, func(line string) bool {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return true
+		}
+		found = match(obj)
+		return !found
+	})
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: %s in output", KeywordNotFound, desc)
+	}
+	return err
+}
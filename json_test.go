@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newJSONOutput(t *testing.T, lines string) *AccumulatedOutput {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte(lines))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	return out
+}
+
+func TestWaitForTemplate(t *testing.T) {
+	out := newJSONOutput(t, `starting
+{"level":"info","event":"init"}
+{"level":"warn","event":"ready"}
+{"level":"info","event":"ready","port":8080}
+`)
+	ctx := context.TODO()
+	require.NoError(t, out.WaitForTemplate(ctx, `{{and (eq .level "info") (eq .event "ready")}}`))
+	require.ErrorIs(t, out.WaitForTemplate(ctx, `{{and (eq .level "error") (eq .event "ready")}}`), KeywordNotFound)
+	require.Error(t, out.WaitForTemplate(ctx, `{{`))
+}
+
+func TestWaitForJSONField(t *testing.T) {
+	out := newJSONOutput(t, `plain text line
+{"event":"starting","port":8080}
+{not json
+{"event":"ready","port":8080,"tags":["a","b"]}
+`)
+	ctx := context.TODO()
+	require.NoError(t, out.WaitForJSONField(ctx, "event", "ready"))
+	require.NoError(t, out.WaitForJSONField(ctx, "port", 8080))
+	require.NoError(t, out.WaitForJSONField(ctx, "tags", []string{"a", "b"}))
+	require.ErrorIs(t, out.WaitForJSONField(ctx, "event", "stopped"), KeywordNotFound)
+	require.ErrorIs(t, out.WaitForJSONField(ctx, "missing", "ready"), KeywordNotFound)
+}
+
+func TestWaitForJSONSchema(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"required": ["level", "event", "port"],
+		"properties": {
+			"level": {"enum": ["info", "debug"]},
+			"event": {"const": "ready"},
+			"port": {"type": "integer", "minimum": 1}
+		}
+	}`)
+	ctx := context.TODO()
+	out := newJSONOutput(t, `starting
+{"level":"info","event":"ready"}
+{"level":"error","event":"ready","port":8080}
+{"level":"info","event":"ready","port":8080}
+`)
+	require.NoError(t, out.WaitForJSONSchema(ctx, schema))
+
+	out = newJSONOutput(t, `{"level":"info","event":"ready","port":0}
+`)
+	require.ErrorIs(t, out.WaitForJSONSchema(ctx, schema), KeywordNotFound)
+	require.Error(t, out.WaitForJSONSchema(ctx, []byte(`{"type": 42}`)))
+}
+
+func TestWaitForNumericField(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- out.WaitForNumericField(ctx, "connected_peers", func(v float64) bool { return v >= 3 })
+	}()
+	for i := 0; i <= 4; i++ {
+		fmt.Fprintf(out, `{"event":"peers","connected_peers":%d}`+"\n", i)
+		fmt.Fprintln(out, `{"event":"tick"}`)
+		fmt.Fprintln(out, `{"event":"peers","connected_peers":"unknown"}`)
+		if i < 3 {
+			select {
+			case err := <-done:
+				t.Fatalf("wait returned at %d peers: %v", i, err)
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}
+	require.NoError(t, <-done)
+
+	require.NoError(t, out.Close())
+	require.ErrorIs(t, out.WaitForNumericField(ctx, "connected_peers", func(v float64) bool { return v > 10 }),
+		KeywordNotFound)
+}
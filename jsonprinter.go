@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONPrinter is an io.Writer that writes each line as a self-contained JSON object, e.g.
+// {"ts":"2024-01-02T15:04:05.123Z","proc":"server","stream":"stdout","line":"ready"}, for machine consumption. Like
+// FormattedPrinter without Redactor, it treats every write as complete lines, an incomplete line becomes a separate object. See
+// Process.SetJSONOutput.
+type JSONPrinter struct {
+	Out    io.Writer
+	Proc   string
+	Stream Stream
+	// Clock is used for the timestamps, SystemClock if nil.
+	Clock Clock
+	// CorrelationID, when set, is written in correlation_id field, see WithContextCorrelationID.
+	CorrelationID string
+}
+
+// jsonLine is the record written by JSONPrinter.
+type jsonLine struct {
+	Time   time.Time `json:"ts"`
+	Proc   string    `json:"proc"`
+	Stream Stream    `json:"stream"`
+	Line   string    `json:"line"`
+	// CorrelationID is omitted when empty.
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+func (j *JSONPrinter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	clock := j.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	now := clock.Now()
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	for _, line := range strings.Split(strings.TrimSuffix(string(p), "\n"), "\n") {
+		if err := enc.Encode(jsonLine{Time: now, Proc: j.Proc, Stream: j.Stream, Line: line, CorrelationID: j.CorrelationID}); err != nil {
+			return 0, err
+		}
+	}
+	// One write per chunk, so the lines of different streams are not mixed.
+	if _, err := j.Out.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SetJSONOutput replaces the text mirror of stdout and stderr with JSON lines written to out, see JSONPrinter. The
+// writes of both streams are serialized, so out does not need to be safe for concurrent use. It should be called
+// before the process is started.
+func (p *Process) SetJSONOutput(out io.Writer, clock Clock) {
+	w := &lockedWriter{w: out}
+	id := p.printer.CorrelationID
+	p.stdout.out = &JSONPrinter{Out: w, Proc: p.shortName, Stream: StreamStdout, Clock: clock, CorrelationID: id}
+	p.stderr.out = &JSONPrinter{Out: w, Proc: p.shortName, Stream: StreamStderr, Clock: clock, CorrelationID: id}
+}
+
+// lockedWriter serializes writes to w.
+type lockedWriter struct {
+	m sync.Mutex
+	w io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,44 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPrinter(t *testing.T) {
+	var out bytes.Buffer
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)}
+	printer := &JSONPrinter{Out: &out, Proc: "api", Stream: StreamStderr, Clock: clock}
+	_, err := printer.Write([]byte("one\n\"quoted\"\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, `{"ts":"2024-01-02T15:04:05Z","proc":"api","stream":"stderr","line":"one"}`+"\n"+
+		`{"ts":"2024-01-02T15:04:05Z","proc":"api","stream":"stderr","line":"\"quoted\""}`+"\n", out.String())
+}
+
+func TestSetJSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo hello && sleep 0.1 && echo oops 1>&2")
+	require.NoError(t, err)
+	p.SetJSONOutput(&out, nil)
+	require.NoError(t, p.Run())
+
+	var records []jsonLine
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r jsonLine
+		require.NoError(t, json.Unmarshal([]byte(line), &r))
+		require.False(t, r.Time.IsZero())
+		r.Time = time.Time{}
+		records = append(records, r)
+	}
+	require.Equal(t, []jsonLine{
+		{Proc: "bash", Stream: StreamStdout, Line: "hello"},
+		{Proc: "bash", Stream: StreamStderr, Line: "oops"},
+	}, records)
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MatcherSet matches lines of the output against many keywords at once. It uses the Aho-Corasick automaton, so a
+// line is scanned once regardless of the number of keywords, unlike strings.Contains per keyword. It is safe for
+// concurrent use.
+type MatcherSet struct {
+	source   *AccumulatedOutput
+	patterns []string
+	// delta is the transition table of the automaton, states are indexes in it, 0 is the root.
+	delta [][256]int32
+	// match is the index of the pattern found when the state is reached, or -1.
+	match []int
+	// empty is the index of the empty pattern, which matches any line, or -1.
+	empty int
+}
+
+// NewMatcherSet returns a set of patterns to scan the output for, see WaitForAny.
+func (s *AccumulatedOutput) NewMatcherSet(patterns ...string) *MatcherSet {
+	m := &MatcherSet{
+		source:   s,
+		patterns: patterns,
+		empty:    -1,
+	}
+	m.build()
+	return m
+}
+
+// build constructs the automaton: the trie of the patterns, the failure links and the transition table.
+func (m *MatcherSet) build() {
+	type node struct {
+		children map[byte]int
+		fail     int
+		// out is the index of the pattern that ends in the node, or -1.
+		out int
+	}
+	nodes := []node{{children: map[byte]int{}, out: -1}}
+	for i, pattern := range m.patterns {
+		if pattern == "" {
+			if m.empty < 0 {
+				m.empty = i
+			}
+			continue
+		}
+		cur := 0
+		for j := 0; j < len(pattern); j++ {
+			next, ok := nodes[cur].children[pattern[j]]
+			if !ok {
+				next = len(nodes)
+				nodes = append(nodes, node{children: map[byte]int{}, out: -1})
+				nodes[cur].children[pattern[j]] = next
+			}
+			cur = next
+		}
+		if nodes[cur].out < 0 {
+			nodes[cur].out = i
+		}
+	}
+
+	m.delta = make([][256]int32, len(nodes))
+	m.match = make([]int, len(nodes))
+	// The states are visited in breadth first order, so the failure state is complete before its dependants.
+	queue := []int{0}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		fail := nodes[cur].fail
+		m.match[cur] = nodes[cur].out
+		if m.match[cur] < 0 && cur != 0 {
+			// A pattern that is a suffix of the path to the state matches too.
+			m.match[cur] = m.match[fail]
+		}
+		for b := 0; b < 256; b++ {
+			if next, ok := nodes[cur].children[byte(b)]; ok {
+				if cur != 0 {
+					nodes[next].fail = int(m.delta[fail][b])
+				}
+				m.delta[cur][b] = int32(next)
+				queue = append(queue, next)
+			} else if cur != 0 {
+				m.delta[cur][b] = m.delta[fail][b]
+			}
+		}
+	}
+}
+
+// Match returns the pattern found in line, if any. If several patterns are found, it returns the one that ends first
+// in the line, and the longest of them if they end at the same position.
+func (m *MatcherSet) Match(line string) (string, bool) {
+	if m.empty >= 0 {
+		return m.patterns[m.empty], true
+	}
+	state := int32(0)
+	for i := 0; i < len(line); i++ {
+		state = m.delta[state][line[i]]
+		if p := m.match[state]; p >= 0 {
+			return m.patterns[p], true
+		}
+	}
+	return "", false
+}
+
+// WaitForAny scans the output for any of the patterns. It returns the pattern found in the first matching line, see
+// Match, or KeywordNotFound when the output is closed without a match.
+func (m *MatcherSet) WaitForAny(ctx context.Context) (string, error) {
+	var matched string
+	found := false
+	err := m.source.eachLine(ctx, func(line string) bool {
+		matched, found = m.Match(line)
+		return !found
+	})
+	if found {
+		return matched, nil
+	}
+	if errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("%w: none of %d patterns in output", KeywordNotFound, len(m.patterns))
+	}
+	return "", err
+}
@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcherSetMatch(t *testing.T) {
+	m := NewAccumulatedOutput(io.Discard).NewMatcherSet("he", "she", "his", "hers", "panic:", "OOM")
+	tests := []struct {
+		line  string
+		match string
+		found bool
+	}{
+		{"ushers", "she", true},
+		{"this", "his", true},
+		{"a panic: boom", "panic:", true},
+		{"killed by OOM", "OOM", true},
+		{"hhe", "he", true},
+		{"nothing here", "he", true},
+		{"nothing at all", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		match, found := m.Match(tt.line)
+		require.Equal(t, tt.found, found, tt.line)
+		require.Equal(t, tt.match, match, tt.line)
+	}
+}
+
+func TestMatcherSetManyPatterns(t *testing.T) {
+	patterns := make([]string, 100)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("error-%03d", i)
+	}
+	out := NewAccumulatedOutput(io.Discard)
+	m := out.NewMatcherSet(patterns...)
+	// Each pattern is found, and only it, compared with the naive scan.
+	for i := 0; i < 1000; i++ {
+		line := fmt.Sprintf("line %d: error-%03d happened", i, i%150)
+		match, found := m.Match(line)
+		naive, naiveFound := naiveMatch(patterns, line)
+		require.Equal(t, naiveFound, found, line)
+		require.Equal(t, naive, match, line)
+	}
+
+	ctx := context.TODO()
+	_, _ = io.WriteString(out, "starting\nerror-120 is not watched\nfailed with error-042\nerror-007\n")
+	match, err := m.WaitForAny(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "error-042", match)
+
+	require.NoError(t, out.Close())
+	_, err = out.NewMatcherSet("missing", "absent").WaitForAny(ctx)
+	require.ErrorIs(t, err, KeywordNotFound)
+}
+
+// naiveMatch returns the first pattern contained in line.
+func naiveMatch(patterns []string, line string) (string, bool) {
+	for _, p := range patterns {
+		if strings.Contains(line, p) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// benchmarkPatterns returns error signatures to watch and lines that do not contain them.
+func benchmarkPatterns() ([]string, []string) {
+	patterns := make([]string, 50)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("fatal error E%04d", i)
+	}
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("2024-01-01T00:00:00Z INFO request %d served in %dms by worker %d", i, i%97, i%8)
+	}
+	return patterns, lines
+}
+
+func BenchmarkMatcherSet(b *testing.B) {
+	patterns, lines := benchmarkPatterns()
+	m := NewAccumulatedOutput(io.Discard).NewMatcherSet(patterns...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			m.Match(line)
+		}
+	}
+}
+
+func BenchmarkNaiveMatch(b *testing.B) {
+	patterns, lines := benchmarkPatterns()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			naiveMatch(patterns, line)
+		}
+	}
+}
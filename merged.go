@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mergedScanner scans lines of multiple outputs ordered by the time they were written.
+type mergedScanner struct {
+	m    sync.Mutex
+	outs []*AccumulatedOutput
+	// next is the index of the next unscanned line of each output.
+	next []int
+}
+
+// MergedScanner returns a scanner of the time ordered lines of several outputs, e.g. outputs of different
+// processes. The clock is set and timed lines are enabled on every output, see SetTimedLines, so the line timestamps
+// are comparable, it should be called before the outputs receive data. Unlike the scanner of a single output, it remembers the position: the next
+// WaitForKeyword continues after the line matched by the previous one. It makes it possible to check the order of
+// the events across processes.
+func MergedScanner(clock Clock, outs ...*AccumulatedOutput) OutputScanner {
+	for _, o := range outs {
+		o.SetClock(clock)
+		o.SetTimedLines(true)
+	}
+	return &mergedScanner{
+		outs: outs,
+		next: make([]int, len(outs)),
+	}
+}
+
+// WaitForKeyword scans the merged lines for given substr. It exits with nil when substr is found and with
+// KeywordNotFound when all outputs are closed and substr is not found.
+func (s *mergedScanner) WaitForKeyword(ctx context.Context, substr string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	t := time.NewTicker(DefaultWakeInterval)
+	defer t.Stop()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pending := make([][]TimedLine, len(s.outs))
+		allClosed := true
+		for i, o := range s.outs {
+			var closed bool
+			pending[i], closed = o.linesSince(s.next[i])
+			allClosed = allClosed && closed
+		}
+		for {
+			i := earliest(pending)
+			if i < 0 {
+				break
+			}
+			line := pending[i][0].Line
+			pending[i] = pending[i][1:]
+			s.next[i]++
+			if strings.Contains(line, substr) {
+				return nil
+			}
+		}
+		if allClosed {
+			return fmt.Errorf("%w '%s' in merged output", KeywordNotFound, substr)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// earliest returns the index of the list which first line is the earliest, or -1 if all lists are empty. On a tie
+// the list with the lower index wins.
+func earliest(lists [][]TimedLine) int {
+	result := -1
+	for i, l := range lists {
+		if len(l) == 0 {
+			continue
+		}
+		if result < 0 || l[0].Time.Before(lists[result][0].Time) {
+			result = i
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergedScannerOrder(t *testing.T) {
+	ctx := context.TODO()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	a := NewAccumulatedOutput(io.Discard)
+	b := NewAccumulatedOutput(io.Discard)
+	before := MergedScanner(clock, a, b)
+	after := MergedScanner(clock, a, b)
+
+	_, err := b.Write([]byte("b: listening\n"))
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, err = a.Write([]byte("a: connected\n"))
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, err = b.Write([]byte("b: request served\n"))
+	require.NoError(t, err)
+	require.NoError(t, a.Close())
+
+	// b started listening before a connected.
+	require.NoError(t, before.WaitForKeyword(ctx, "listening"))
+	require.NoError(t, before.WaitForKeyword(ctx, "connected"))
+
+	// The live line is matched once it is written.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		clock.Advance(time.Second)
+		_, _ = b.Write([]byte("b: shutdown\n"))
+		_ = b.Close()
+	}()
+	require.NoError(t, before.WaitForKeyword(ctx, "shutdown"))
+
+	// Nothing is listening after a connected.
+	require.NoError(t, after.WaitForKeyword(ctx, "connected"))
+	require.ErrorIs(t, after.WaitForKeyword(ctx, "listening"), KeywordNotFound)
+}
@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+)
+
+// Option configures a Process.
+type Option func(*Process) error
+
+// Apply applies options to the process. It should be called before the process is started.
+func (p *Process) Apply(opts ...Option) error {
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithAutoKillOnGC kills the process if the Process is garbage collected while the process is still running. It is
+// a backstop against leaked processes, e.g. when the goroutine that started the process panics, and logs a warning
+// when it triggers. It is not a replacement for explicit Kill: finalizers run at the discretion of the garbage
+// collector, possibly much later or never, and helpers running in background, like FailOnStderr, keep the Process
+// reachable until they complete.
+func WithAutoKillOnGC() Option {
+	return func(p *Process) error {
+		runtime.SetFinalizer(p, func(p *Process) {
+			if p.Status() != StatusRunning {
+				return
+			}
+			log.Printf("WARNING: process '%s' leaked, killing it on garbage collection", p.shortName)
+			if err := p.KillWith(os.Kill); err != nil {
+				log.Println(err)
+			}
+		})
+		return nil
+	}
+}
+
+// WithReadChunkSize sets the size of reads made by the helpers that scan or copy the output of the process, e.g.
+// Stream. See AccumulatedOutput.SetReadChunkSize.
+func WithReadChunkSize(n int) Option {
+	return func(p *Process) error {
+		if n < 0 {
+			return fmt.Errorf("invalid read chunk size %d", n)
+		}
+		p.stdout.SetReadChunkSize(n)
+		p.stderr.SetReadChunkSize(n)
+		p.combined.SetReadChunkSize(n)
+		return nil
+	}
+}
+
+// WithTimedLines records the lines of stdout, stderr and the combined output with the time they were written. See
+// AccumulatedOutput.SetTimedLines.
+func WithTimedLines() Option {
+	return func(p *Process) error {
+		p.stdout.SetTimedLines(true)
+		p.stderr.SetTimedLines(true)
+		p.combined.SetTimedLines(true)
+		return nil
+	}
+}
+
+// WithTempDir runs the process in a new temporary directory, see Dir. The directory is created right away, so it can
+// be prepared before the process is started. It is removed with its content when the process exits, after the
+// OnExit hooks, and Restart creates a new empty one. If the process is never started, the directory is left behind.
+// ChangeDirectory removes the directory and the process runs in the given one instead.
+func WithTempDir() Option {
+	return func(p *Process) error {
+		return p.newTempDir()
+	}
+}
+
+// newTempDir creates a temporary directory and makes it the working directory of the process.
+func (p *process) newTempDir() error {
+	dir, err := os.MkdirTemp("", "runner-"+p.shortName+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory for %s: %w", p.shortName, err)
+	}
+	p.tempDir = dir
+	p.cmd.Dir = dir
+	return nil
+}
+
+// removeTempDir removes the temporary directory created by WithTempDir. Only that directory is removed, whatever the
+// working directory of the process is.
+func (p *process) removeTempDir() {
+	if p.tempDir == "" {
+		return
+	}
+	if err := os.RemoveAll(p.tempDir); err != nil {
+		log.Printf("failed to remove temporary directory of %s: %v", p.shortName, err)
+	}
+}
+
+// WithBackground runs the process without collecting its output, e.g. a mock dependency that only needs to be alive.
+// The output goes to the null device, so there are no pipes to read and nothing is accumulated or printed, the
+// outputs of the process stay empty and are closed on exit. The lifecycle is managed as usual, e.g. IsAlive, Kill,
+// Done and Restart.
+func WithBackground() Option {
+	return func(p *Process) error {
+		p.background = true
+		p.cmd.Stdout = nil
+		p.cmd.Stderr = nil
+		return nil
+	}
+}
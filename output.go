@@ -2,59 +2,624 @@ package runner
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var KeywordNotFound = errors.New("failed to find keyword")
 
+// ErrLineOutOfRange is returned by LineAt when the output is closed before the requested line is available.
+var ErrLineOutOfRange = errors.New("line index out of range")
+
+// ErrDebounceAborted is returned by WaitForKeywordDebounced when the abort keyword appears within the debounce window.
+var ErrDebounceAborted = errors.New("keyword followed by abort keyword")
+
+// ErrScannersCancelled is returned by the pending waits, e.g. WaitForKeyword, cancelled by CancelScans.
+var ErrScannersCancelled = errors.New("scanners cancelled")
+
+// ErrAborted is returned by WaitForKeywordDone when the wait is aborted via done channel.
+var ErrAborted = errors.New("wait aborted")
+
 // The AccumulatedOutput is a tool that helps accumulate output of the process and provides search capability. It is
 // useful for application tests.
 type AccumulatedOutput struct {
 	out io.Writer
 	buf MultiReaderBuffer
+	// opts are the options of the buffer, they are applied again by Restart.
+	opts   []BufferOption
+	paused atomic.Bool
+
+	// lm protects timed lines, they are recorded only when timed is set, see SetTimedLines.
+	lm      sync.Mutex
+	timed   bool
+	clock   Clock
+	partial []byte
+	lines   []TimedLine
+	// linesClosed is set when the output is closed and no more lines are expected.
+	linesClosed bool
+
+	// readChunk is the size of reads from the buffer made by the helpers, zero means the default.
+	readChunk int
+	// partialMatch is set by SetPartialLineMatch.
+	partialMatch bool
+
+	// scanCtx is cancelled by CancelScans to abort the pending scans, sm protects it.
+	sm         sync.Mutex
+	scanCtx    context.Context
+	scanCancel context.CancelFunc
 }
 
-func NewAccumulatedOutput(out io.Writer) *AccumulatedOutput {
-	buf := NewMultiReaderBuffer()
+// defaultReadChunk is the size of reads made by WriteTo by default.
+const defaultReadChunk = 32 * 1024
+
+// NewAccumulatedOutput returns AccumulatedOutput that mirrors the data to out. Options are applied to the
+// underlying buffer.
+func NewAccumulatedOutput(out io.Writer, opts ...BufferOption) *AccumulatedOutput {
 	return &AccumulatedOutput{
-		out: io.MultiWriter(out, buf),
-		buf: buf,
+		out:   out,
+		buf:   NewMultiReaderBuffer(opts...),
+		opts:  opts,
+		clock: SystemClock,
 	}
 }
 
+// Write mirrors p to the output writer, unless mirroring is paused, and accumulates it in the buffer.
 func (s *AccumulatedOutput) Write(p []byte) (int, error) {
-	return s.out.Write(p)
+	if !s.paused.Load() {
+		if _, err := s.out.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	n, err := s.buf.Write(p)
+	if err != nil {
+		return n, err
+	}
+	s.recordLines(p)
+	return n, nil
+}
+
+// PauseMirror stops forwarding writes to the output writer. The data is still accumulated.
+func (s *AccumulatedOutput) PauseMirror() {
+	s.paused.Store(true)
+}
+
+// ResumeMirror restores forwarding writes to the output writer.
+func (s *AccumulatedOutput) ResumeMirror() {
+	s.paused.Store(false)
 }
 
+// Close closes the output. The mirror is flushed, if it holds an incomplete line, see FormattedPrinter.Redactor.
 func (s *AccumulatedOutput) Close() error {
-	return s.buf.Close()
+	s.flushLines()
+	var err error
+	if f, ok := s.out.(flusher); ok {
+		err = f.Flush()
+	}
+	return errors.Join(err, s.buf.Close())
+}
+
+// flusher is a mirror that holds back data until it is flushed, e.g. FormattedPrinter.
+type flusher interface {
+	Flush() error
+}
+
+// NewFilteredReader returns a reader of the output that yields only the lines for which match returns true, each
+// followed by a new line character. It reads the output from the beginning and returns io.EOF when the output is
+// closed.
+func (s *AccumulatedOutput) NewFilteredReader(match func(line string) bool) io.ReadCloser {
+	r := s.NewReader()
+	return &filteredReader{
+		reader:  r,
+		scanner: s.newScanner(r),
+		match:   match,
+	}
+}
+
+type filteredReader struct {
+	reader  io.ReadCloser
+	scanner *bufio.Scanner
+	match   func(line string) bool
+	// pending is the matched data not read yet.
+	pending []byte
+}
+
+func (r *filteredReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if !r.scanner.Scan() {
+			if err := r.scanner.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		if line := r.scanner.Text(); r.match(line) {
+			r.pending = append(r.pending, line...)
+			r.pending = append(r.pending, '\n')
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *filteredReader) Close() error {
+	return r.reader.Close()
+}
+
+// NewRateLimitedReader returns a reader of the output that reads at most bytesPerSec bytes per second on average. It
+// simulates a slow consumer, e.g. to observe the buffer limits.
+func (s *AccumulatedOutput) NewRateLimitedReader(bytesPerSec int) io.ReadCloser {
+	return &rateLimitedReader{
+		reader: s.NewReader(),
+		rate:   max(bytesPerSec, 1),
+	}
+}
+
+type rateLimitedReader struct {
+	reader io.ReadCloser
+	rate   int
+	// start is the time of the first read, total is the number of bytes read since then.
+	start time.Time
+	total int
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	// Read in pieces of 1/10 of a second, so the reads are spread evenly.
+	if chunk := max(r.rate/10, 1); len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.reader.Read(p)
+	r.total += n
+	due := r.start.Add(time.Duration(float64(r.total) / float64(r.rate) * float64(time.Second)))
+	time.Sleep(time.Until(due))
+	return n, err
+}
+
+func (r *rateLimitedReader) Close() error {
+	return r.reader.Close()
+}
+
+// SetReadChunkSize sets the size of reads from the buffer made by the helpers that scan or copy the output, e.g.
+// WaitForKeyword, Stream or WriteTo. Larger chunks mean fewer reads and lock acquisitions, smaller chunks deliver
+// the data in smaller pieces. Zero restores the default. It should be called before the output is read.
+func (s *AccumulatedOutput) SetReadChunkSize(n int) {
+	s.readChunk = n
+}
+
+// chunkSize returns the configured size of reads, or the default.
+func (s *AccumulatedOutput) chunkSize() int {
+	if s.readChunk > 0 {
+		return s.readChunk
+	}
+	return defaultReadChunk
+}
+
+// newScanner returns a line scanner of r that reads with the configured chunk size.
+func (s *AccumulatedOutput) newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	if s.readChunk > 0 {
+		scanner.Buffer(make([]byte, s.readChunk), max(s.readChunk, bufio.MaxScanTokenSize))
+	}
+	return scanner
+}
+
+// WriteTo writes the output to w from the beginning until the output is closed. It implements io.WriterTo.
+func (s *AccumulatedOutput) WriteTo(w io.Writer) (int64, error) {
+	r := s.NewReader()
+	defer r.Close()
+	return io.CopyBuffer(w, struct{ io.Reader }{r}, make([]byte, s.chunkSize()))
 }
 
 func (s *AccumulatedOutput) NewReader() io.ReadCloser {
 	return s.buf.NewReader()
 }
 
+// Checkpoint returns the current position in the output, i.e. number of bytes accumulated so far. It can be used
+// later with ScannerAt to continue scanning from that point.
+func (s *AccumulatedOutput) Checkpoint() int {
+	return s.buf.Len()
+}
+
+// ScannerAt returns a scanner that starts scanning at a given byte offset, usually obtained with Checkpoint. The
+// offset is expected to point to the start of a line.
+func (s *AccumulatedOutput) ScannerAt(offset int) *StreamScanner {
+	scanner := NewStreamScanner(s.buf.NewReaderAt(offset))
+	scanner.output = s
+	return scanner
+}
+
+// Tail returns up to n last lines of the output accumulated so far. An incomplete last line is included.
+func (s *AccumulatedOutput) Tail(n int) []string {
+	data := strings.TrimSuffix(string(s.Snapshot()), "\n")
+	if data == "" || n <= 0 {
+		return nil
+	}
+	lines := strings.Split(data, "\n")
+	return lines[max(len(lines)-n, 0):]
+}
+
+// Snapshot returns a copy of the output accumulated so far. Unlike reading, it does not wait for the output to be
+// closed.
+func (s *AccumulatedOutput) Snapshot() []byte {
+	return s.buf.Snapshot()
+}
+
 // WaitForKeyword scans the output stream for given substr. It is a blocking call.
 // It exits with nil when substr is found. It exits with KeywordNotFound it is not found, and the output stream is closed.
+// Every write is delivered to the waiting readers immediately, so a complete line is matched as soon as the process
+// writes it, there is no need to wait for more output or for the process to exit.
 func (s *AccumulatedOutput) WaitForKeyword(ctx context.Context, substr string) error {
+	ctx, cancel := s.withScanCancel(ctx)
+	defer cancel()
+	return scanError(ctx, s.scanForKeyword(ctx, substr))
+}
+
+// scanForKeyword implements WaitForKeyword.
+func (s *AccumulatedOutput) scanForKeyword(ctx context.Context, substr string) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
+	if s.partialMatch {
+		return s.waitForKeywordPartial(ctx, substr)
+	}
+	r := s.NewReader()
+	defer r.Close()
 	cr := &cancellableReader{
-		reader: s.NewReader(),
+		reader: r,
 		ctx:    ctx,
 	}
-	scanner := bufio.NewScanner(cr)
+	scanner := s.newScanner(cr)
 	return waitForKeyword(ctx, scanner, substr)
 }
 
+// CancelScans aborts all pending scans of the output, e.g. WaitForKeyword, without cancelling their contexts. The
+// aborted scans return ErrScannersCancelled. The scans started later are not affected.
+func (s *AccumulatedOutput) CancelScans() {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+	if s.scanCancel != nil {
+		s.scanCancel()
+		s.scanCtx, s.scanCancel = nil, nil
+	}
+}
+
+// withScanCancel returns ctx that is also cancelled by CancelScans with ErrScannersCancelled cause.
+func (s *AccumulatedOutput) withScanCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	s.sm.Lock()
+	if s.scanCtx == nil {
+		s.scanCtx, s.scanCancel = context.WithCancel(context.Background())
+	}
+	scanCtx := s.scanCtx
+	s.sm.Unlock()
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(scanCtx, func() {
+		cancel(ErrScannersCancelled)
+	})
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// scanError replaces the error of a scan aborted by CancelScans with ErrScannersCancelled.
+func scanError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), ErrScannersCancelled) {
+		return ErrScannersCancelled
+	}
+	return err
+}
+
+// SetPartialLineMatch makes WaitForKeyword match the incomplete last line, i.e. data after the last new line
+// character, as soon as it arrives. By default a line is matched only when it is complete or the output is closed,
+// so a prompt printed without a new line by a process that then blocks is never matched. It should be called before
+// the scan.
+func (s *AccumulatedOutput) SetPartialLineMatch(enabled bool) {
+	s.partialMatch = enabled
+}
+
+// waitForKeywordPartial implements WaitForKeyword that matches the incomplete last line whenever new data arrives.
+func (s *AccumulatedOutput) waitForKeywordPartial(ctx context.Context, substr string) error {
+	r := s.NewReader()
+	defer r.Close()
+	cr := r.(ContextReader)
+	chunk := make([]byte, s.chunkSize())
+	// line is the incomplete line read so far.
+	var line []byte
+	for {
+		n, err := cr.ReadContext(ctx, chunk)
+		data := chunk[:n]
+		for {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			line = append(line, data[:i]...)
+			if strings.Contains(string(line), substr) {
+				return nil
+			}
+			line = line[:0]
+			data = data[i+1:]
+		}
+		line = append(line, data...)
+		if strings.Contains(string(line), substr) {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w '%s' in output", KeywordNotFound, substr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WaitForKeywordOffset is the same as WaitForKeyword, but it also returns the byte offset of the start of the matched
+// line. The offset counts from the beginning of the output, like Checkpoint, so it can be passed to ScannerAt to scan
+// again from the matched line.
+func (s *AccumulatedOutput) WaitForKeywordOffset(ctx context.Context, substr string) (int, error) {
+	ctx, cancel := s.withScanCancel(ctx)
+	defer cancel()
+	offset, err := s.scanForKeywordOffset(ctx, substr)
+	return offset, scanError(ctx, err)
+}
+
+// scanForKeywordOffset implements WaitForKeywordOffset.
+func (s *AccumulatedOutput) scanForKeywordOffset(ctx context.Context, substr string) (int, error) {
+	r := s.NewReader()
+	defer r.Close()
+	scanner := s.newScanner(&cancellableReader{reader: r, ctx: ctx})
+	// start is the offset of the last scanned line, next is the offset of the line after it.
+	start, next := 0, 0
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance > 0 {
+			start, next = next, next+advance
+		}
+		return advance, token, err
+	})
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), substr) {
+			if sc, ok := r.(SkipCounter); ok {
+				// The dropped lines were not scanned, but they count in the offset.
+				start += sc.Skipped()
+			}
+			return start, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%w '%s' in output", KeywordNotFound, substr)
+}
+
+// WaitForBytesPattern scans the raw bytes of the output for pattern, regardless of the lines. It is useful for binary
+// protocols. The pattern is found even if it is split between writes. It exits with nil when the pattern is found and
+// with KeywordNotFound when the output is closed without it.
+func (s *AccumulatedOutput) WaitForBytesPattern(ctx context.Context, pattern []byte) error {
+	ctx, cancel := s.withScanCancel(ctx)
+	defer cancel()
+	r := s.NewReader()
+	defer r.Close()
+	cr := r.(ContextReader)
+	// window keeps the tail of the data read so far that may contain the beginning of the pattern.
+	var window []byte
+	chunk := make([]byte, s.chunkSize())
+	for {
+		n, err := cr.ReadContext(ctx, chunk)
+		window = append(window, chunk[:n]...)
+		if bytes.Contains(window, pattern) {
+			return nil
+		}
+		if keep := len(pattern) - 1; len(window) > keep {
+			window = append(window[:0], window[len(window)-keep:]...)
+		}
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w %q in output", KeywordNotFound, pattern)
+		}
+		if err != nil {
+			return scanError(ctx, err)
+		}
+	}
+}
+
+// WaitForBytes waits until the output has accumulated at least n bytes, e.g. the handshake of a binary protocol. It
+// returns io.ErrUnexpectedEOF if the output is closed with fewer bytes, or the context error.
+func (s *AccumulatedOutput) WaitForBytes(ctx context.Context, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	ctx, cancel := s.withScanCancel(ctx)
+	defer cancel()
+	// The reader blocks until the byte at offset n-1 is written.
+	r := s.buf.NewReaderAt(n - 1)
+	defer r.Close()
+	_, err := r.(ContextReader).ReadContext(ctx, make([]byte, 1))
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: output closed after %d of %d bytes", io.ErrUnexpectedEOF, s.buf.Len(), n)
+	}
+	return scanError(ctx, err)
+}
+
+// WaitForKeywordDone is the same as WaitForKeyword, but instead of context it uses done channel to abort the
+// wait. It exits with ErrAborted when done is closed before substr is found.
+func (s *AccumulatedOutput) WaitForKeywordDone(done <-chan struct{}, substr string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	err := s.WaitForKeyword(ctx, substr)
+	if err != nil && errors.Is(err, context.Canceled) {
+		return ErrAborted
+	}
+	return err
+}
+
+// WaitForKeywordDebounced waits for substr like WaitForKeyword, but after the match it keeps scanning for debounce
+// duration and fails with ErrDebounceAborted if a line containing abortOn appears in that window. It smooths over
+// startup races like "ready" immediately followed by an error. The window is measured while scanning, so when the
+// output was accumulated before the call, all lines that follow the match are examined at once. If the output is
+// closed within the window, the match is accepted.
+func (s *AccumulatedOutput) WaitForKeywordDebounced(ctx context.Context, substr string, debounce time.Duration, abortOn string) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	errSettled := errors.New("debounce window passed")
+	matched := false
+	aborted := ""
+	var timer *time.Timer
+	err := s.eachLine(ctx, func(line string) bool {
+		if !matched {
+			if strings.Contains(line, substr) {
+				matched = true
+				timer = time.AfterFunc(debounce, func() { cancel(errSettled) })
+			}
+			return true
+		}
+		if strings.Contains(line, abortOn) {
+			aborted = line
+			return false
+		}
+		return true
+	})
+	if timer != nil {
+		timer.Stop()
+	}
+	switch {
+	case aborted != "":
+		return fmt.Errorf("%w: %q after '%s'", ErrDebounceAborted, aborted, substr)
+	case matched && (errors.Is(err, io.EOF) || errors.Is(context.Cause(ctx), errSettled)):
+		return nil
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w '%s' in output", KeywordNotFound, substr)
+	}
+	return err
+}
+
+// MatchStream emits every line of the output that contains substr, starting from the beginning of the output. The
+// channel is closed when the output is closed or ctx is done.
+func (s *AccumulatedOutput) MatchStream(ctx context.Context, substr string) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		_ = s.eachLine(ctx, func(line string) bool {
+			if !strings.Contains(line, substr) {
+				return true
+			}
+			select {
+			case ch <- line:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+// LineAt returns the line with given zero based index. It blocks until the line is available. It exits with
+// ErrLineOutOfRange if the output is closed and has fewer lines.
+func (s *AccumulatedOutput) LineAt(ctx context.Context, index int) (string, error) {
+	if index < 0 {
+		return "", fmt.Errorf("%w: %d", ErrLineOutOfRange, index)
+	}
+	var result string
+	i := 0
+	err := s.eachLine(ctx, func(line string) bool {
+		if i == index {
+			result = line
+			return false
+		}
+		i++
+		return true
+	})
+	if errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("%w: %d, output has %d lines", ErrLineOutOfRange, index, i)
+	}
+	return result, err
+}
+
+// DetectPanic scans the output for a Go panic. When the "panic:" line is found it collects the goroutine stack
+// trace that follows until the blank line that ends the stack, and returns the whole trace. It returns false if
+// the output is closed or ctx is done before a panic is found.
+func (s *AccumulatedOutput) DetectPanic(ctx context.Context) (string, bool) {
+	var trace []string
+	inStack := false
+	_ = s.eachLine(ctx, func(line string) bool {
+		if trace == nil {
+			if strings.HasPrefix(line, "panic: ") {
+				trace = []string{line}
+			}
+			return true
+		}
+		if line == "" {
+			// There is a blank line between the panic message and the stack.
+			if inStack {
+				return false
+			}
+		} else if strings.HasPrefix(line, "goroutine ") {
+			inStack = true
+		}
+		trace = append(trace, line)
+		return true
+	})
+	if trace == nil {
+		return "", false
+	}
+	return strings.Join(trace, "\n"), true
+}
+
+// eachLine calls fn for every line of the output, starting from the beginning, until fn returns false. It blocks
+// waiting for more lines while the output is open. It returns nil when fn stops the iteration, io.EOF when the
+// output is closed, or the context error.
+func (s *AccumulatedOutput) eachLine(ctx context.Context, fn func(line string) bool) error {
+	ctx, cancel := s.withScanCancel(ctx)
+	defer cancel()
+	return scanError(ctx, s.scanLines(ctx, fn))
+}
+
+// scanLines implements eachLine.
+func (s *AccumulatedOutput) scanLines(ctx context.Context, fn func(line string) bool) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	r := s.NewReader()
+	defer r.Close()
+	scanner := s.newScanner(&cancellableReader{reader: r, ctx: ctx})
+	for scanner.Scan() {
+		if !fn(scanner.Text()) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
 type cancellableReader struct {
 	reader io.ReadCloser
 	ctx    context.Context
@@ -65,7 +630,18 @@ type readerResults struct {
 	err error
 }
 
+// Read reads from the reader until ctx is done. Readers of MultiReaderBuffer support the context directly, so the
+// read does not need a goroutine, it matters for the callers that read in small pieces. Other readers are read in a
+// goroutine per call.
 func (r *cancellableReader) Read(p []byte) (int, error) {
+	if cr, ok := r.reader.(ContextReader); ok {
+		n, err := cr.ReadContext(r.ctx, p)
+		if err != nil && r.ctx.Err() != nil && errors.Is(err, r.ctx.Err()) {
+			// Notify the reader that we do not care anymore.
+			_ = r.reader.Close()
+		}
+		return n, err
+	}
 	retCh := make(chan readerResults)
 
 	go func() {
@@ -88,6 +664,8 @@ type StreamScanner struct {
 	source  io.ReadCloser
 	reader  *cancellableReader
 	scanner *bufio.Scanner
+	// output is the output the scanner reads, if it is created by ScannerAt, its scans are aborted by CancelScans.
+	output *AccumulatedOutput
 }
 
 func NewStreamScanner(r io.ReadCloser) *StreamScanner {
@@ -97,15 +675,21 @@ func NewStreamScanner(r io.ReadCloser) *StreamScanner {
 }
 
 func (s *StreamScanner) WaitForKeyword(ctx context.Context, substr string) error {
+	if s.output != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = s.output.withScanCancel(ctx)
+		defer cancel()
+	}
 	// Should we iniialize reader and scanner?
 	if s.reader == nil {
 		s.reader = &cancellableReader{
 			reader: s.source,
-			ctx:    ctx,
 		}
 		s.scanner = bufio.NewScanner(s.reader)
 	}
-	return waitForKeyword(ctx, s.scanner, substr)
+	// The scanner may be reused, the reads are cancelled by the context of the current scan.
+	s.reader.ctx = ctx
+	return scanError(ctx, waitForKeyword(ctx, s.scanner, substr))
 }
 
 func waitForKeyword(ctx context.Context, scanner *bufio.Scanner, substr string) error {
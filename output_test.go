@@ -1,11 +1,15 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -37,3 +41,310 @@ five`
 	require.NoError(t, x.WaitForKeyword(context.TODO(), "five"))
 	require.Error(t, x.WaitForKeyword(context.TODO(), "three"))
 }
+
+func TestWaitForKeywordDoneAborts(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.WaitForKeywordDone(make(chan struct{}), "hello"))
+
+	done := make(chan struct{})
+	result := make(chan error)
+	go func() {
+		result <- out.WaitForKeywordDone(done, "world")
+	}()
+	close(done)
+	require.ErrorIs(t, <-result, ErrAborted)
+}
+
+func TestLineAt(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("one\ntwo\n"))
+	require.NoError(t, err)
+
+	result := make(chan string)
+	go func() {
+		line, err := out.LineAt(context.TODO(), 2)
+		assert.NoError(t, err)
+		result <- line
+	}()
+	_, err = out.Write([]byte("three\nfour\n"))
+	require.NoError(t, err)
+	require.Equal(t, "three", <-result)
+
+	line, err := out.LineAt(context.TODO(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "two", line)
+
+	require.NoError(t, out.Close())
+	_, err = out.LineAt(context.TODO(), 4)
+	require.ErrorIs(t, err, ErrLineOutOfRange)
+}
+
+func TestPauseMirror(t *testing.T) {
+	var console bytes.Buffer
+	out := NewAccumulatedOutput(&console)
+	_, err := out.Write([]byte("before\n"))
+	require.NoError(t, err)
+	out.PauseMirror()
+	_, err = out.Write([]byte("noisy\n"))
+	require.NoError(t, err)
+	out.ResumeMirror()
+	_, err = out.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, "before\nafter\n", console.String())
+	require.Equal(t, "before\nnoisy\nafter\n", string(out.Snapshot()))
+}
+
+func TestDetectPanic(t *testing.T) {
+	trace := `panic: runtime error: index out of range [3] with length 2
+
+goroutine 1 [running]:
+main.main()
+	/src/main.go:8 +0x1d`
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("starting\n" + trace + "\n\nexit status 2\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	captured, ok := out.DetectPanic(context.TODO())
+	require.True(t, ok)
+	require.Equal(t, trace, captured)
+
+	clean := NewAccumulatedOutput(io.Discard)
+	_, err = clean.Write([]byte("no panic here\n"))
+	require.NoError(t, err)
+	require.NoError(t, clean.Close())
+	_, ok = clean.DetectPanic(context.TODO())
+	require.False(t, ok)
+}
+
+func TestScannerAtCheckpoint(t *testing.T) {
+	ctx := context.TODO()
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("early error\nphase one done\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.WaitForKeyword(ctx, "phase one done"))
+	checkpoint := out.Checkpoint()
+
+	_, err = out.Write([]byte("late warning\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	require.NoError(t, out.ScannerAt(checkpoint).WaitForKeyword(ctx, "late warning"))
+	require.ErrorIs(t, out.ScannerAt(checkpoint).WaitForKeyword(ctx, "early error"), KeywordNotFound)
+}
+
+func TestWaitForKeywordDebounced(t *testing.T) {
+	ctx := context.TODO()
+	flappy := NewAccumulatedOutput(io.Discard)
+	go func() {
+		_, _ = flappy.Write([]byte("server ready\n"))
+		time.Sleep(50 * time.Millisecond)
+		_, _ = flappy.Write([]byte("error: port in use\n"))
+	}()
+	err := flappy.WaitForKeywordDebounced(ctx, "ready", 500*time.Millisecond, "error")
+	require.ErrorIs(t, err, ErrDebounceAborted)
+
+	stable := NewAccumulatedOutput(io.Discard)
+	_, err = stable.Write([]byte("server ready\n"))
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, stable.WaitForKeywordDebounced(ctx, "ready", 100*time.Millisecond, "error"))
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestMatchStream(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	go func() {
+		for i := 0; i < 5; i++ {
+			_, _ = fmt.Fprintf(out, "request handled id=%d\n", i)
+			_, _ = fmt.Fprintf(out, "debug tick %d\n", i)
+		}
+		_ = out.Close()
+	}()
+
+	count := 0
+	for line := range out.MatchStream(context.TODO(), "request handled") {
+		require.Contains(t, line, "request handled")
+		count++
+	}
+	require.Equal(t, 5, count)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	matches := out.MatchStream(ctx, "request handled")
+	<-matches
+	cancel()
+	for range matches {
+	}
+}
+
+func TestWaitForBytesPatternAcrossWrites(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	pattern := []byte{0xde, 0xad, 0xbe, 0xef}
+	go func() {
+		_, _ = out.Write([]byte{0x00, 0x01, 0xde, 0xad})
+		time.Sleep(50 * time.Millisecond)
+		_, _ = out.Write([]byte{0xbe, 0xef, 0x02})
+	}()
+	require.NoError(t, out.WaitForBytesPattern(context.TODO(), pattern))
+
+	require.NoError(t, out.Close())
+	require.ErrorIs(t, out.WaitForBytesPattern(context.TODO(), []byte{0xca, 0xfe}), KeywordNotFound)
+}
+
+func TestReadChunkSize(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	out.SetReadChunkSize(7)
+	for i := 0; i < 100; i++ {
+		_, _ = fmt.Fprintf(out, "line %d\n", i)
+	}
+	require.NoError(t, out.Close())
+	require.NoError(t, out.WaitForKeyword(context.TODO(), "line 99"))
+	// The pattern spans several chunks.
+	require.NoError(t, out.WaitForBytesPattern(context.TODO(), []byte("line 98\nline 99")))
+	var copied bytes.Buffer
+	n, err := out.WriteTo(&copied)
+	require.NoError(t, err)
+	require.Equal(t, int64(out.Checkpoint()), n)
+	require.Equal(t, out.Snapshot(), copied.Bytes())
+}
+
+func BenchmarkReadChunkSize(b *testing.B) {
+	out := NewAccumulatedOutput(io.Discard)
+	line := strings.Repeat("x", 99) + "\n"
+	for i := 0; i < 100000; i++ {
+		_, _ = io.WriteString(out, line)
+	}
+	_ = out.Close()
+	for _, size := range []int{512, 4 * 1024, 64 * 1024} {
+		out.SetReadChunkSize(size)
+		b.Run(fmt.Sprintf("WriteTo/%d", size), func(b *testing.B) {
+			b.SetBytes(int64(out.Checkpoint()))
+			for i := 0; i < b.N; i++ {
+				_, _ = out.WriteTo(io.Discard)
+			}
+		})
+		b.Run(fmt.Sprintf("Scan/%d", size), func(b *testing.B) {
+			b.SetBytes(int64(out.Checkpoint()))
+			for i := 0; i < b.N; i++ {
+				_ = out.eachLine(context.TODO(), func(string) bool { return true })
+			}
+		})
+	}
+}
+
+func TestNewFilteredReader(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("INFO start\nWARN disk low\nINFO tick\nWARN retry"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	r := out.NewFilteredReader(func(line string) bool {
+		return strings.Contains(line, "WARN")
+	})
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "WARN disk low\nWARN retry\n", string(data))
+}
+
+func BenchmarkReadOneByte(b *testing.B) {
+	buf := NewMultiReaderBuffer()
+	_, _ = buf.Write(bytes.Repeat([]byte("x"), 64*1024))
+	_ = buf.Close()
+	readAll := func(r io.Reader) {
+		p := make([]byte, 1)
+		for {
+			if _, err := r.Read(p); err != nil {
+				return
+			}
+		}
+	}
+	b.Run("Direct", func(b *testing.B) {
+		b.SetBytes(int64(buf.Len()))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			readAll(buf.NewReader())
+		}
+	})
+	b.Run("Cancellable", func(b *testing.B) {
+		b.SetBytes(int64(buf.Len()))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			readAll(&cancellableReader{reader: buf.NewReader(), ctx: context.TODO()})
+		}
+	})
+	b.Run("CancellableGoroutine", func(b *testing.B) {
+		b.SetBytes(int64(buf.Len()))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			// Hide ReadContext to force a goroutine per read.
+			reader := struct{ io.ReadCloser }{buf.NewReader()}
+			readAll(&cancellableReader{reader: reader, ctx: context.TODO()})
+		}
+	})
+}
+
+func TestNewRateLimitedReader(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write(bytes.Repeat([]byte("x"), 1000))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	r := out.NewRateLimitedReader(2000)
+	defer r.Close()
+	start := time.Now()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Len(t, data, 1000)
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, elapsed, 450*time.Millisecond)
+	require.Less(t, elapsed, time.Second)
+}
+
+func TestWaitForKeywordOffset(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	_, _ = io.WriteString(out, "one\r\ntwo\nthree: ready\nfour\n")
+	ctx := context.TODO()
+	offset, err := out.WaitForKeywordOffset(ctx, "ready")
+	require.NoError(t, err)
+	require.Equal(t, len("one\r\ntwo\n"), offset)
+	require.True(t, bytes.HasPrefix(out.Snapshot()[offset:], []byte("three: ready\n")))
+	require.NoError(t, out.ScannerAt(offset).WaitForKeyword(ctx, "four"))
+
+	require.NoError(t, out.Close())
+	_, err = out.WaitForKeywordOffset(ctx, "missing")
+	require.ErrorIs(t, err, KeywordNotFound)
+}
+
+func TestWaitForKeywordOffsetDroppedLines(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard, WithMaxLines(2))
+	_, _ = io.WriteString(out, "one\ntwo\nthree\nfour: ready\n")
+	require.NoError(t, out.Close())
+	offset, err := out.WaitForKeywordOffset(context.TODO(), "ready")
+	require.NoError(t, err)
+	require.Equal(t, len("one\ntwo\nthree\n"), offset)
+}
+
+func TestWaitForBytes(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- out.WaitForBytes(ctx, 6)
+	}()
+	_, _ = io.WriteString(out, "abc")
+	select {
+	case err := <-done:
+		t.Fatalf("wait returned after 3 bytes: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, _ = io.WriteString(out, "def")
+	require.NoError(t, <-done)
+
+	require.NoError(t, out.Close())
+	require.ErrorIs(t, out.WaitForBytes(ctx, 7), io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// Pause stops the process with SIGSTOP, e.g. to simulate scheduler delays. The process stays alive, see IsPaused.
+func (p *Process) Pause() error {
+	return p.setPaused(syscall.SIGSTOP, true)
+}
+
+// Resume continues the process stopped by Pause with SIGCONT.
+func (p *Process) Resume() error {
+	return p.setPaused(syscall.SIGCONT, false)
+}
+
+func (p *Process) setPaused(sig syscall.Signal, paused bool) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if p.proc == nil {
+		return errors.New("process is not running")
+	}
+	if p.status != StatusRunning {
+		return fmt.Errorf("process %s is %s", p.shortName, p.status)
+	}
+	if err := p.proc.Signal(sig); err != nil {
+		return fmt.Errorf("failed to send %s to process %s: %w", sig, p.shortName, err)
+	}
+	p.paused = paused
+	return nil
+}
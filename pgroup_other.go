@@ -0,0 +1,26 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"os"
+)
+
+// WithProcessGroup is not supported on this platform.
+func WithProcessGroup() Option {
+	return func(*Process) error {
+		return errors.ErrUnsupported
+	}
+}
+
+// groupAlive returns false, process groups are not supported on this platform.
+func (p *process) groupAlive() bool {
+	return false
+}
+
+// terminate sends the terminating signal to the process.
+func (p *process) terminate(sig os.Signal) error {
+	p.snapshotDescendants()
+	return p.osProcess().Signal(sig)
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// WithProcessGroup starts the process in its own process group, and Kill, KillWith and Stop signal the whole group,
+// so the children of the process, e.g. a server started by a shell script, do not outlive it. Other signals, e.g.
+// sent with Signal, reach only the process itself.
+func WithProcessGroup() Option {
+	return func(p *Process) error {
+		if p.cmd.SysProcAttr == nil {
+			p.cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		p.cmd.SysProcAttr.Setpgid = true
+		p.processGroup = true
+		return nil
+	}
+}
+
+// groupAlive returns true if the process group, see WithProcessGroup, still has members, e.g. after the leader exits.
+func (p *process) groupAlive() bool {
+	proc := p.osProcess()
+	if !p.processGroup || proc == nil {
+		return false
+	}
+	return !errors.Is(syscall.Kill(-proc.Pid, 0), syscall.ESRCH)
+}
+
+// terminate sends the terminating signal to the process, or to its process group, see WithProcessGroup.
+func (p *process) terminate(sig os.Signal) error {
+	p.snapshotDescendants()
+	proc := p.osProcess()
+	s, ok := sig.(syscall.Signal)
+	if !p.processGroup || !ok {
+		return proc.Signal(sig)
+	}
+	// The process group ID is the PID of the process, the negative PID addresses the group.
+	err := syscall.Kill(-proc.Pid, s)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrStartupTimeout is returned by WaitForKeywordPolicy when the first line does not appear within the startup
+// timeout.
+var ErrStartupTimeout = errors.New("no output within startup timeout")
+
+// ErrIdleTimeout is returned by WaitForKeywordPolicy when the output is idle longer than the idle timeout.
+var ErrIdleTimeout = errors.New("output idle longer than idle timeout")
+
+// ScanPolicy describes timeouts of a scan that distinguishes the startup of the process from its steady state. Zero
+// values mean no limit.
+type ScanPolicy struct {
+	// Startup limits the wait for the first line of the output.
+	Startup time.Duration
+	// Idle limits the gap between the lines after the first one.
+	Idle time.Duration
+	// Deadline limits the whole scan, it fails with context.DeadlineExceeded.
+	Deadline time.Duration
+}
+
+// WaitForKeywordPolicy is the same as WaitForKeyword, but the wait is limited by the policy. It exits with
+// ErrStartupTimeout if the output is silent for longer than the startup timeout, then with ErrIdleTimeout if the
+// gap between the lines is longer than the idle timeout.
+func (s *AccumulatedOutput) WaitForKeywordPolicy(ctx context.Context, substr string, policy ScanPolicy) error {
+	if policy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Deadline)
+		defer cancel()
+	}
+	ctx, cancel := s.withScanCancel(ctx)
+	defer cancel()
+	r := s.NewDeadlineReader()
+	defer r.Close()
+	// A deadline in the past aborts the blocked read when ctx is done.
+	past := time.Unix(1, 0)
+	stop := context.AfterFunc(ctx, func() { _ = r.SetReadDeadline(past) })
+	defer stop()
+	phase, limit := ErrStartupTimeout, policy.Startup
+	setDeadline := func() {
+		deadline := time.Time{}
+		if limit > 0 {
+			deadline = time.Now().Add(limit)
+		}
+		_ = r.SetReadDeadline(deadline)
+		if ctx.Err() != nil {
+			// ctx may be done after the deadline is set above, do not override the abort.
+			_ = r.SetReadDeadline(past)
+		}
+	}
+	setDeadline()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), substr) {
+			return nil
+		}
+		phase, limit = ErrIdleTimeout, policy.Idle
+		setDeadline()
+	}
+	err := scanner.Err()
+	switch {
+	case ctx.Err() != nil:
+		return scanError(ctx, ctx.Err())
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return fmt.Errorf("%w %s while waiting for '%s'", phase, limit, substr)
+	case err != nil:
+		return err
+	}
+	return fmt.Errorf("%w '%s' in output", KeywordNotFound, substr)
+}
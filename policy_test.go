@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForKeywordPolicy(t *testing.T) {
+	ctx := context.TODO()
+	policy := ScanPolicy{
+		Startup:  time.Second,
+		Idle:     100 * time.Millisecond,
+		Deadline: 5 * time.Second,
+	}
+	write := func(out *AccumulatedOutput, delays []time.Duration, lines ...string) {
+		for i, line := range lines {
+			time.Sleep(delays[i])
+			_, _ = out.Write([]byte(line + "\n"))
+		}
+	}
+
+	// Slow startup within the startup window, then regular lines.
+	ready := NewAccumulatedOutput(io.Discard)
+	go write(ready, []time.Duration{300 * time.Millisecond, 20 * time.Millisecond, 20 * time.Millisecond},
+		"booting", "loading", "ready")
+	require.NoError(t, ready.WaitForKeywordPolicy(ctx, "ready", policy))
+
+	// The same startup, then a gap longer than the idle timeout.
+	stuck := NewAccumulatedOutput(io.Discard)
+	go write(stuck, []time.Duration{300 * time.Millisecond, 20 * time.Millisecond, 500 * time.Millisecond},
+		"booting", "loading", "ready")
+	require.ErrorIs(t, stuck.WaitForKeywordPolicy(ctx, "ready", policy), ErrIdleTimeout)
+
+	// No output at all.
+	silent := NewAccumulatedOutput(io.Discard)
+	policy.Startup = 100 * time.Millisecond
+	require.ErrorIs(t, silent.WaitForKeywordPolicy(ctx, "ready", policy), ErrStartupTimeout)
+
+	// The overall deadline.
+	policy = ScanPolicy{Deadline: 100 * time.Millisecond}
+	require.ErrorIs(t, silent.WaitForKeywordPolicy(ctx, "ready", policy), context.DeadlineExceeded)
+}
@@ -4,25 +4,180 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
-// FormattedPrinter is a custom io.Writer that formats the output with a prefix.
+// FormattedPrinter is a custom io.Writer that formats the output with a prefix. It is safe to call Write from
+// multiple goroutines, the fields should not be changed after the first Write, except the prefix with SetPrefix.
+// Every write is printed as complete lines, unless Redactor is set.
 type FormattedPrinter struct {
+	m      sync.Mutex
 	Out    io.Writer
 	Prefix string
+	// Redactor, when set, rewrites each line before it is printed. It only affects the printed output, the
+	// accumulated buffer keeps the original line, so it is still possible to search for the redacted content. A line
+	// is redacted and printed only when it is complete, so a secret split between writes is not printed in pieces.
+	// The incomplete last line is held until a following write completes it or Flush is called.
+	Redactor func(line string) string
+	// MaxLineChars, when positive, truncates printed lines longer than that many characters and appends a note
+	// with the number of dropped characters. The accumulated buffer keeps the full line.
+	MaxLineChars int
+	// Tags, when set, are printed after the prefix of each line as key=value pairs sorted by key, e.g. for log
+	// aggregation.
+	Tags map[string]string
+	// ElapsedSince, when set, adds the time elapsed since then to each line, e.g. [+1.203s]. The time is taken from
+	// Clock.
+	ElapsedSince time.Time
+	// Clock is used for the elapsed time, SystemClock if nil.
+	Clock Clock
+	// CorrelationID, when set, is printed before the tags as correlation_id=<id>, see WithContextCorrelationID.
+	CorrelationID string
+
+	// partial is the incomplete last line held when Redactor is set.
+	partial []byte
+}
+
+// SetPrefix changes the prefix of the lines printed after the call.
+func (f *FormattedPrinter) SetPrefix(prefix string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.Prefix = prefix
 }
 
 func (f *FormattedPrinter) Write(p []byte) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	if err := f.write(&f.partial, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush prints the incomplete last line held by the Redactor, if any.
+func (f *FormattedPrinter) Flush() error {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.flush(&f.partial)
+}
+
+// write prints p, with Redactor set only the complete lines are printed and the rest is kept in partial. It should
+// be called with f.m locked.
+func (f *FormattedPrinter) write(partial *[]byte, p []byte) error {
+	if f.Redactor != nil {
+		data := append(*partial, p...)
+		i := bytes.LastIndexByte(data, '\n')
+		if i < 0 {
+			*partial = data
+			return nil
+		}
+		*partial = append([]byte(nil), data[i+1:]...)
+		p = data[:i]
+	}
+	return f.print(p)
+}
+
+// flush prints the incomplete line kept in partial. It should be called with f.m locked.
+func (f *FormattedPrinter) flush(partial *[]byte) error {
+	if len(*partial) == 0 {
+		return nil
+	}
+	p := *partial
+	*partial = nil
+	return f.print(p)
+}
+
+// print prints each line of p with the prefix. It should be called with f.m locked.
+func (f *FormattedPrinter) print(p []byte) error {
+	var tags string
+	if !f.ElapsedSince.IsZero() {
+		clock := f.Clock
+		if clock == nil {
+			clock = SystemClock
+		}
+		tags = fmt.Sprintf("[+%.3fs] ", clock.Now().Sub(f.ElapsedSince).Seconds())
+	}
+	switch {
+	case f.CorrelationID != "" && len(f.Tags) > 0:
+		tags += "correlation_id=" + f.CorrelationID + " " + formatTags(f.Tags) + " | "
+	case f.CorrelationID != "":
+		tags += "correlation_id=" + f.CorrelationID + " | "
+	case len(f.Tags) > 0:
+		tags += formatTags(f.Tags) + " | "
+	}
 	// Split to multiple lines
 	lines := bytes.Split(p, []byte("\n"))
 	for _, line := range lines {
-		_, err := fmt.Fprintf(f.Out, "%-16.16s| %s\n", f.Prefix, line)
+		if f.Redactor != nil {
+			line = []byte(f.Redactor(string(line)))
+		}
+		if f.MaxLineChars > 0 {
+			line = truncateLine(line, f.MaxLineChars)
+		}
+		_, err := fmt.Fprintf(f.Out, "%-16.16s| %s%s\n", f.Prefix, tags, line)
 		if err != nil {
-			return 0, err
+			return err
 		}
 	}
+	return nil
+}
+
+// printerStream writes to the printer with its own incomplete line, so the streams that share the printer, e.g.
+// stdout and stderr of the process, do not complete the lines of each other.
+type printerStream struct {
+	f       *FormattedPrinter
+	partial []byte
+}
+
+func (s *printerStream) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	s.f.m.Lock()
+	defer s.f.m.Unlock()
+	if err := s.f.write(&s.partial, p); err != nil {
+		return 0, err
+	}
 	return len(p), nil
 }
+
+// Flush prints the incomplete last line of the stream, if any.
+func (s *printerStream) Flush() error {
+	s.f.m.Lock()
+	defer s.f.m.Unlock()
+	return s.f.flush(&s.partial)
+}
+
+// formatTags renders tags as space separated key=value pairs sorted by key.
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + tags[k]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// truncateLine cuts the line to maxChars characters and appends the number of dropped characters.
+func truncateLine(line []byte, maxChars int) []byte {
+	total := utf8.RuneCount(line)
+	if total <= maxChars {
+		return line
+	}
+	cut := 0
+	for i := 0; i < maxChars; i++ {
+		_, size := utf8.DecodeRune(line[cut:])
+		cut += size
+	}
+	return fmt.Appendf(line[:cut:cut], "…(truncated %d chars)", total-maxChars)
+}
@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactorMasksMirrorOnly(t *testing.T) {
+	var console bytes.Buffer
+	printer := &FormattedPrinter{
+		Out:    &console,
+		Prefix: "app",
+		Redactor: func(line string) string {
+			return strings.ReplaceAll(line, "sk-secret-token", "***")
+		},
+	}
+	out := NewAccumulatedOutput(printer)
+	_, err := out.Write([]byte("starting with key sk-secret-token\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	require.Contains(t, console.String(), "starting with key ***")
+	require.NotContains(t, console.String(), "sk-secret-token")
+	require.NoError(t, out.WaitForKeyword(context.TODO(), "sk-secret-token"))
+}
+
+func TestRedactorSecretSplitBetweenWrites(t *testing.T) {
+	var console bytes.Buffer
+	printer := &FormattedPrinter{
+		Out:    &console,
+		Prefix: "app",
+		Redactor: func(line string) string {
+			return strings.ReplaceAll(line, "sk-secret-token", "***")
+		},
+	}
+	out := NewAccumulatedOutput(printer)
+	for _, s := range []string{"key sk-sec", "ret-token\nnext sk-secret", "-token"} {
+		_, err := out.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	require.Equal(t, "app             | key ***\n", console.String())
+	require.NoError(t, out.Close())
+
+	require.Equal(t, "app             | key ***\napp             | next ***\n", console.String())
+}
+
+func TestMaxLineCharsTruncatesMirrorOnly(t *testing.T) {
+	var console bytes.Buffer
+	printer := &FormattedPrinter{
+		Out:          &console,
+		Prefix:       "app",
+		MaxLineChars: 10,
+	}
+	out := NewAccumulatedOutput(printer)
+	blob := strings.Repeat("ж", 1000)
+	_, err := out.Write([]byte("short\n" + blob))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	require.Equal(t, "app             | short\napp             | "+strings.Repeat("ж", 10)+"…(truncated 990 chars)\n",
+		console.String())
+	require.Equal(t, "short\n"+blob, string(out.Snapshot()))
+}
+
+func TestTagsAreSorted(t *testing.T) {
+	var console bytes.Buffer
+	printer := &FormattedPrinter{
+		Out:    &console,
+		Prefix: "api",
+		Tags:   map[string]string{"service": "api", "env": "test", "zone": "a"},
+	}
+	_, err := printer.Write([]byte("one\ntwo"))
+	require.NoError(t, err)
+
+	require.Equal(t, "api             | env=test service=api zone=a | one\n"+
+		"api             | env=test service=api zone=a | two\n", console.String())
+}
+
+func TestElapsedSince(t *testing.T) {
+	var console bytes.Buffer
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	printer := &FormattedPrinter{
+		Out:          &console,
+		Prefix:       "app",
+		ElapsedSince: clock.Now(),
+		Clock:        clock,
+	}
+	_, err := printer.Write([]byte("start"))
+	require.NoError(t, err)
+	clock.Advance(1203 * time.Millisecond)
+	_, err = printer.Write([]byte("ready"))
+	require.NoError(t, err)
+
+	require.Equal(t, "app             | [+0.000s] start\napp             | [+1.203s] ready\n", console.String())
+}
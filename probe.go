@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ReadinessProbe tells when the started process is ready to serve. Probe blocks until the process is ready, the
+// probe fails, or ctx is done. Probe method value can be passed to ProcessGroup.SetReadiness.
+type ReadinessProbe interface {
+	Probe(ctx context.Context) error
+}
+
+// ProbeFunc adapts a function to ReadinessProbe.
+type ProbeFunc func(ctx context.Context) error
+
+// Probe calls f.
+func (f ProbeFunc) Probe(ctx context.Context) error {
+	return f(ctx)
+}
+
+// probe is a built-in ReadinessProbe, the description is used in ReadinessError.
+type probe struct {
+	expected string
+	probe    func(ctx context.Context) error
+}
+
+func (p probe) Probe(ctx context.Context) error {
+	return p.probe(ctx)
+}
+
+func (p probe) String() string {
+	return p.expected
+}
+
+// MarkerProbe returns the probe that waits until the marker appears in stdout or stderr, see WaitForKeywordOrExit.
+func (p *Process) MarkerProbe(marker string) ReadinessProbe {
+	return probe{
+		expected: fmt.Sprintf("keyword '%s' in output", marker),
+		probe: func(ctx context.Context) error {
+			return p.WaitForKeywordOrExit(ctx, marker)
+		},
+	}
+}
+
+// PortProbe returns the probe that waits until a TCP connection to addr succeeds, see WaitForPort.
+func (p *Process) PortProbe(addr string) ReadinessProbe {
+	return probe{
+		expected: fmt.Sprintf("port %s is open", addr),
+		probe: func(ctx context.Context) error {
+			return p.WaitForPort(ctx, addr)
+		},
+	}
+}
+
+// HTTPProbe returns the probe that waits until GET url responds with 200 OK, see WaitForHealthy.
+func (p *Process) HTTPProbe(url string) ReadinessProbe {
+	return probe{
+		expected: fmt.Sprintf("GET %s returns 200", url),
+		probe: func(ctx context.Context) error {
+			return p.WaitForHealthy(ctx, url)
+		},
+	}
+}
+
+// FileProbe returns the probe that waits until the file at path exists, see WaitForFile.
+func (p *Process) FileProbe(path string) ReadinessProbe {
+	return probe{
+		expected: fmt.Sprintf("file %s exists", path),
+		probe: func(ctx context.Context) error {
+			return p.WaitForFile(ctx, path)
+		},
+	}
+}
+
+// StartAndWaitReady starts the process and waits until the probe succeeds. If the process does not become ready, it
+// returns ReadinessError: of ReadinessExited kind if the process exits, of ReadinessTimeout kind if ctx is done first,
+// or of ReadinessProbeFailed kind if the probe fails, unless the probe returns ReadinessError itself. The process
+// that is still running is killed then.
+func (p *Process) StartAndWaitReady(ctx context.Context, probe ReadinessProbe) error {
+	if err := p.Start(); err != nil {
+		return err
+	}
+	expected := "readiness probe"
+	if s, ok := probe.(fmt.Stringer); ok {
+		expected = s.String()
+	}
+	probeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		result <- probe.Probe(probeCtx)
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-p.Done():
+		select {
+		case err = <-result:
+		default:
+			// The probe may not watch the process.
+			return p.readinessError(ReadinessExited, expected, ErrExitedEarly)
+		}
+	}
+	if err == nil {
+		log.Println(p.shortName, "is ready:", expected)
+		return nil
+	}
+	var re *ReadinessError
+	switch {
+	case errors.As(err, &re):
+	case ctx.Err() != nil:
+		re = p.readinessError(ReadinessTimeout, expected, err)
+	default:
+		re = p.readinessError(ReadinessProbeFailed, expected, err)
+	}
+	if re.Kind != ReadinessExited && !p.Exited() {
+		p.killNotReady(expected)
+	}
+	return re
+}
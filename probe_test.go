@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartAndWaitReady(t *testing.T) {
+	ctx := context.TODO()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	pidFile := filepath.Join(t.TempDir(), "app.pid")
+
+	probes := []func(p *Process) ReadinessProbe{
+		func(p *Process) ReadinessProbe { return p.MarkerProbe("listening") },
+		func(p *Process) ReadinessProbe { return p.PortProbe(l.Addr().String()) },
+		func(p *Process) ReadinessProbe { return p.HTTPProbe(healthy.URL) },
+		func(p *Process) ReadinessProbe { return p.FileProbe(pidFile) },
+		func(p *Process) ReadinessProbe { return ProbeFunc(func(ctx context.Context) error { return nil }) },
+	}
+	for _, probe := range probes {
+		p, err := NewProcess(ctx, "bash", "-c", "sleep 0.1 && echo $$ > "+pidFile+" && echo listening && sleep 5")
+		require.NoError(t, err)
+		require.NoError(t, p.StartAndWaitReady(ctx, probe(p)))
+		require.NoError(t, p.Kill())
+		<-p.Done()
+		require.NoError(t, os.RemoveAll(pidFile))
+	}
+}
+
+func TestStartAndWaitReadyFailures(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo bad config && exit 2")
+	require.NoError(t, err)
+	err = p.StartAndWaitReady(ctx, p.FileProbe(filepath.Join(t.TempDir(), "missing")))
+	re := requireReadinessKind(t, err, ReadinessExited)
+	require.Equal(t, 2, re.ExitCode)
+	require.Contains(t, err.Error(), "bad config")
+
+	// A probe that does not watch the process still detects the exit.
+	p, err = NewProcess(ctx, "bash", "-c", "exit 3")
+	require.NoError(t, err)
+	err = p.StartAndWaitReady(ctx, ProbeFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	requireReadinessKind(t, err, ReadinessExited)
+
+	timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	p, err = NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	err = p.StartAndWaitReady(timeout, p.MarkerProbe("never"))
+	requireReadinessKind(t, err, ReadinessTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	<-p.Done()
+	require.Equal(t, ReasonKilled, p.ExitReason())
+
+	p, err = NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	err = p.StartAndWaitReady(ctx, ProbeFunc(func(ctx context.Context) error {
+		return errors.New("migrations failed")
+	}))
+	requireReadinessKind(t, err, ReadinessProbeFailed)
+	require.ErrorContains(t, err, "migrations failed")
+	<-p.Done()
+	require.Equal(t, ReasonKilled, p.ExitReason())
+}
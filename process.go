@@ -2,6 +2,7 @@ package runner
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -10,140 +11,791 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
+	"weak"
 )
 
+// ErrExitedEarly is returned when the process exits while it is expected to be running.
+var ErrExitedEarly = errors.New("process exited early")
+
+// ErrNotStarted is returned when the process is expected to be started, e.g. by Signal.
+var ErrNotStarted = errors.New("process not started")
+
+// ErrAlreadyStarted is returned by Start when the process is already started. Use Restart to run it again.
+var ErrAlreadyStarted = errors.New("process already started")
+
+// Status describes the lifecycle stage of a Process.
+type Status int
+
+const (
+	StatusNotStarted Status = iota
+	StatusRunning
+	// StatusExited means the process exited on its own.
+	StatusExited
+	// StatusKilled means the process exited after Kill or KillWith.
+	StatusKilled
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusNotStarted:
+		return "not started"
+	case StatusRunning:
+		return "running"
+	case StatusExited:
+		return "exited"
+	case StatusKilled:
+		return "killed"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Process runs a command and accumulates its output. The state of the process is kept in a separate struct, so
+// background goroutines, e.g. the one waiting for the exit, do not keep the Process reachable. It allows to detect
+// leaked processes, see WithAutoKillOnGC.
 type Process struct {
+	*process
+}
+
+type process struct {
 	shortName string
+	ctx       context.Context
 	cmd       *exec.Cmd
+	printer   *FormattedPrinter
 	stdout    *AccumulatedOutput
 	stderr    *AccumulatedOutput
+	// combined accumulates both stdout and stderr in the order of arrival.
+	combined *AccumulatedOutput
+	// done is closed when the process exits, waitErr is the result of cmd.Wait.
+	done    chan struct{}
+	waitErr error
+
+	m      sync.Mutex
+	status Status
+	killed bool
+	// paused is set by Pause and cleared by Resume or exit.
+	paused    bool
+	startedAt time.Time
+	exitedAt  time.Time
+	exitCode  int
+	reason    ExitReason
+	// restarts and lastErr are maintained by Supervise, they are kept across restarts.
+	restarts int
+	lastErr  error
+
+	// tagged is set by WithTaggedOutput.
+	tagged *taggedOutput
+	// proc is the OS process of the current run, it is set when the process is started.
+	proc *os.Process
+	// descendants are the descendants of the current run seen so far, PID to start time, see Descendants.
+	descendants map[int]uint64
+	// abortDrain, when set, closes the read ends of the pipes drained by the process, see awaitDrained.
+	abortDrain func()
+
+	crMode      CRMode
+	streamClose StreamClose
+	// onStarted are the hooks registered with OnStarted.
+	onStarted []func(pid int) error
+	// onExit are the hooks registered with OnExit.
+	onExit []func()
+
+	// successCodes are non-zero exit codes that are treated as success, see SetSuccessCodes.
+	successCodes []int
+
+	// env are the environment variables set on the process, envOrder is the order the names were first set in.
+	// envFilter selects the inherited variables, see environ.
+	env       map[string]string
+	envOrder  []string
+	envFilter func(key string) bool
+	// strictEnv is set by WithStrictEnv, envErr is the duplicate variable error reported by Start.
+	strictEnv bool
+	envErr    error
+	// secrets are names of environment variables which values should not be printed.
+	secrets map[string]bool
+	// exitSummary is set by SetExitSummary.
+	exitSummary bool
+	// killCause is the cause passed to KillWithCause.
+	killCause error
+	// exitCtx is cancelled by exitCancel when the process exits, see ExitContext.
+	exitCtx    context.Context
+	exitCancel context.CancelCauseFunc
+	// stdinPipe is set when stdin is created for a single run, by StdinPipe or WithPTY.
+	stdinPipe bool
+	// onFailure is set by SetFailureHandler.
+	onFailure FailureHandler
+	// ptyMode is set by WithPTY, ptmx is the controlling side of the terminal of the running process and ptyRows
+	// and ptyCols are its size set with SetWindowSize.
+	ptyMode bool
+	ptmx    *os.File
+	ptyRows uint16
+	ptyCols uint16
+	// processGroup is set by WithProcessGroup.
+	processGroup bool
+	// background is set by WithBackground.
+	background bool
+	// tempDir is the directory created by WithTempDir, it is removed on exit. Empty if the process does not run in a
+	// temporary directory.
+	tempDir string
+	// stopSignal and stopGrace are set by SetStopSequence, stopped is set when the sequence is started.
+	stopped    bool
+	stopSignal os.Signal
+	stopGrace  time.Duration
+	// readinessSlot is the position of the readiness pipe in cmd.ExtraFiles, counting from one, set by
+	// WithReadinessFD. readinessFD is the read end of the pipe of the current run.
+	readinessSlot int
+	readinessFD   *os.File
 }
 
 func NewProcess(ctx context.Context, name string, args ...string) (*Process, error) {
 	_, fileName := path.Split(name)
-	cmd := exec.CommandContext(ctx, name, args...)
-	c := cmd.Cancel
-	cmd.Cancel = func() error {
-		log.Println("Cancel called for ", fileName)
-		return c()
-	}
+	cmd := newCommand(name, args...)
 	// Pipe stdout and stderr of the process to the test execution stderr.
 	testOutput := &FormattedPrinter{
 		Out:    os.Stderr,
 		Prefix: fileName,
 	}
-	stdout := NewAccumulatedOutput(testOutput)
-	cmd.Stdout = stdout
-	stderr := NewAccumulatedOutput(testOutput)
-	cmd.Stderr = stderr
-	return &Process{
-		shortName: fileName,
-		cmd:       cmd,
-		stdout:    stdout,
-		stderr:    stderr,
-	}, nil
+	stdout := NewAccumulatedOutput(&printerStream{f: testOutput})
+	stderr := NewAccumulatedOutput(&printerStream{f: testOutput})
+	combined := NewAccumulatedOutput(io.Discard)
+	cmd.Stdout = io.MultiWriter(stdout, combined)
+	cmd.Stderr = io.MultiWriter(stderr, combined)
+	exitCtx, exitCancel := context.WithCancelCause(context.Background())
+	return &Process{&process{
+		exitCtx:    exitCtx,
+		exitCancel: exitCancel,
+		shortName:  fileName,
+		ctx:        ctx,
+		cmd:        cmd,
+		printer:    testOutput,
+		stdout:     stdout,
+		stderr:     stderr,
+		combined:   combined,
+		done:       make(chan struct{}),
+		exitCode:   -1,
+	}}, nil
 }
 
+// newCommand returns the command of the process. It is not bound to the context, the process is stopped when the
+// context is done by watchContext.
+func newCommand(name string, args ...string) *exec.Cmd {
+	return exec.Command(name, args...)
+}
+
+// ChangeDirectory sets the working directory of the process. It replaces the temporary directory created by
+// WithTempDir, which is removed right away.
 func (p *Process) ChangeDirectory(path string) {
+	if p.tempDir != "" {
+		p.removeTempDir()
+		p.tempDir = ""
+	}
 	p.cmd.Dir = path
 }
 
+// SetStdin sets the reader stdin of the process is read from, e.g. a strings.Reader with the commands for an
+// interactive tool. The default, nil, is the null device. It should be called before the process is started.
+func (p *Process) SetStdin(r io.Reader) {
+	p.cmd.Stdin = r
+	p.stdinPipe = false
+}
+
+// StdinPipe returns a pipe connected to stdin of the process, to write to it while the process runs. Closing the pipe
+// signals EOF to the process, it is closed automatically when the process exits. It should be called before the
+// process is started, and again after Restart.
+func (p *Process) StdinPipe() (io.WriteCloser, error) {
+	w, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.stdinPipe = true
+	return w, nil
+}
+
+// Dir returns the working directory of the process, set by ChangeDirectory or WithTempDir. Empty means the working
+// directory of the current process.
+func (p *Process) Dir() string {
+	return p.cmd.Dir
+}
+
+// ResolvedPath returns the absolute path of the executable that will be run, i.e. the name resolved with
+// exec.LookPath when the process is created. It returns the lookup error if the executable is not found. A relative
+// path is resolved against the directory set by ChangeDirectory.
+func (p *Process) ResolvedPath() (string, error) {
+	if p.cmd.Err != nil {
+		return "", p.cmd.Err
+	}
+	resolved := p.cmd.Path
+	if !filepath.IsAbs(resolved) && p.cmd.Dir != "" {
+		resolved = filepath.Join(p.cmd.Dir, resolved)
+	}
+	return filepath.Abs(resolved)
+}
+
+// SetOutput sets the writer the formatted output of the process is printed to, the default is os.Stderr. See
+// TBWriter to print to the test log. It should be called before the process is started.
+func (p *Process) SetOutput(w io.Writer) {
+	p.printer.Out = w
+}
+
+// SetRedactor sets a function that rewrites each line of the process output before it is printed. The
+// accumulated output is not affected. It should be called before the process is started.
+func (p *Process) SetRedactor(r func(line string) string) {
+	p.printer.Redactor = r
+}
+
+// SetTags sets key=value tags printed on each line of the process output, see FormattedPrinter.Tags. It should be
+// called before the process is started.
+func (p *Process) SetTags(tags map[string]string) {
+	p.printer.Tags = tags
+}
+
+// SetPrefixOnKeyword changes the prefix of the printed output to newPrefix once a line that contains substr
+// appears in stdout or stderr. It is useful when a process changes its role, e.g. a supervisor that becomes a
+// worker. The line with the keyword itself may still be printed with the old prefix. The watch stops when the
+// process context is done.
+func (p *Process) SetPrefixOnKeyword(substr, newPrefix string) {
+	_, _, combined := p.outputs()
+	go func() {
+		_ = combined.eachLine(p.ctx, func(line string) bool {
+			if strings.Contains(line, substr) {
+				p.printer.SetPrefix(newPrefix)
+				return false
+			}
+			return true
+		})
+	}()
+}
+
+// Start starts the process. It blocks if the limit set by SetMaxConcurrent is reached. It returns ErrAlreadyStarted
+// if the process is running or has exited.
 func (p *Process) Start() error {
-	err := p.cmd.Start()
+	if status := p.Status(); status != StatusNotStarted {
+		return fmt.Errorf("%w: %s is %s", ErrAlreadyStarted, p.shortName, status)
+	}
+	if p.envErr != nil {
+		return p.envErr
+	}
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	release, err := acquireSlot(p.ctx)
+	if err != nil {
+		return err
+	}
+	started := func() {}
+	p.abortDrain = nil
+	// drained, when set, is closed when the output is drained from the pipes owned by the process.
+	var drained <-chan struct{}
+	// A background process writes to the null device, there are no pipes to drain.
+	if p.ptyMode && !p.background {
+		if started, drained, err = ptyPipes(p.process); err != nil {
+			release()
+			return err
+		}
+	} else if p.tagged != nil && !p.background {
+		if started, err = p.tagged.pipes(p.process); err != nil {
+			release()
+			return err
+		}
+		drained = p.tagged.done
+	} else if p.streamClose == CloseOnEOF && !p.background {
+		if started, drained, err = eofPipes(p.process); err != nil {
+			release()
+			return err
+		}
+	}
+	closeReadiness, err := p.openReadinessFD()
+	if err != nil {
+		started()
+		release()
+		return err
+	}
+	p.cmd.Env = p.environ()
+	err = p.cmd.Start()
+	started()
+	closeReadiness()
 	if err != nil {
+		release()
 		return err
 	}
+	p.m.Lock()
+	p.proc = p.cmd.Process
+	p.status = StatusRunning
+	p.startedAt = time.Now()
+	p.m.Unlock()
 	log.Printf("process '%s' started", p.shortName)
+	go p.wait(release, drained)
+	go p.watchContext(p.done)
+	for _, hook := range p.onStarted {
+		if err := hook(p.cmd.Process.Pid); err != nil {
+			if killErr := p.KillWithCause(os.Kill, fmt.Errorf("post start hook failed: %w", err)); killErr == nil {
+				<-p.done
+			}
+			return fmt.Errorf("post start hook of %s failed: %w", p.shortName, err)
+		}
+	}
 	return nil
 }
 
+// OnStarted registers a hook that is called with the pid right after the process is started, before any readiness
+// check, e.g. to register the process with a discovery mock. If the hook returns an error, the process is killed and
+// Start returns the error. The hooks are called in the order they are registered, also on Restart.
+func (p *Process) OnStarted(hook func(pid int) error) {
+	p.onStarted = append(p.onStarted, hook)
+}
+
+// OnExit registers a hook that is called after the process exits, when its exit code and status are known and
+// before the waits for the exit return, e.g. to clean up resources of the process. The hooks are called in the order
+// they are registered, on every exit.
+func (p *Process) OnExit(hook func()) {
+	p.onExit = append(p.onExit, hook)
+}
+
+// wait waits for the process to exit, closes the output streams according to the StreamClose mode and releases the
+// concurrency slot.
+func (p *process) wait(release func(), drained <-chan struct{}) {
+	err := p.cmd.Wait()
+	switch {
+	case drained == nil:
+		p.closeOutputs()
+	case p.streamClose == CloseOnEOF:
+		// The command writes to the pipes directly, so Wait does not wait for the output to be drained.
+		go func() {
+			<-drained
+			p.closeOutputs()
+		}()
+	default:
+		p.awaitDrained(drained)
+		p.closeOutputs()
+	}
+	release()
+	p.m.Lock()
+	p.exitedAt = time.Now()
+	p.exitCode, p.reason = p.classifyExit(err)
+	if p.reason == ReasonSuccess {
+		// The exit code may be declared successful with SetSuccessCodes.
+		err = nil
+	}
+	p.waitErr = p.withStderr(err)
+	if p.killed {
+		p.status = StatusKilled
+	} else {
+		p.status = StatusExited
+	}
+	p.paused = false
+	summary := p.exitSummary
+	exitCancel, cause := p.exitCancel, p.exitCause()
+	p.m.Unlock()
+	exitCancel(cause)
+	if summary {
+		p.printExitSummary()
+	}
+	for _, hook := range p.onExit {
+		hook()
+	}
+	p.removeTempDir()
+	close(p.done)
+}
+
 // StartAsync executes the process and starts processing its stderr. It signals that process exits via waitDone.
+// Like Start, it returns ErrAlreadyStarted if the process is already started, waitDone is not changed then. An
+// unexpected exit, see RunUntilExit, is reported to the failure handler, see SetFailureHandler.
 func (p *Process) StartAsync(waitDone *sync.WaitGroup) error {
 	if err := p.Start(); err != nil {
 		return err
 	}
 	waitDone.Add(1)
+	// The goroutine keeps only the state of the process, like wait, so the Process can be garbage collected, see
+	// WithAutoKillOnGC.
+	inner, owner := p.process, weak.Make(p)
 	go func() {
 		defer waitDone.Done()
-		p.RunUntilExit()
+		if err := inner.runUntilExit(); err != nil {
+			p := owner.Value()
+			if p == nil {
+				// The Process was collected after the exit, the handler gets a new one with the same state.
+				p = &Process{inner}
+			}
+			p.fail(err)
+		}
 	}()
 	return nil
 }
 
+// StartAndConfirmAlive starts the process like StartAsync and waits for settle duration. It is a simple readiness
+// check for processes without readiness signal: it returns ErrExitedEarly with the output tail if the process
+// exits before settle passes.
+func (p *Process) StartAndConfirmAlive(waitDone *sync.WaitGroup, settle time.Duration) error {
+	if err := p.StartAsync(waitDone); err != nil {
+		return err
+	}
+	t := time.NewTimer(settle)
+	defer t.Stop()
+	select {
+	case <-p.Done():
+		summary := p.Summary()
+		return fmt.Errorf("%w: %s exited within %s with code %d, output:\n%s", ErrExitedEarly, p.shortName, settle,
+			summary.ExitCode, strings.Join(summary.Tail, "\n"))
+	case <-t.C:
+		return nil
+	}
+}
+
 // RunWithMarker starts the process and waits until a given marker string appears in the stderr.
-// It is a typical way to execute applications.
+// It is a typical way to execute applications. If the process does not become ready, it returns ReadinessError: of
+// ReadinessExited kind if the process exits, or of ReadinessTimeout kind if ctx is done first, then the process is
+// killed.
 func (p *Process) RunWithMarker(ctx context.Context, waitDone *sync.WaitGroup, marker string) error {
 	if err := p.StartAsync(waitDone); err != nil {
 		return err
 	}
 	err := p.StdErrScanner().WaitForKeyword(ctx, marker)
 	if err != nil {
-		if !errors.Is(err, KeywordNotFound) {
-			p.Kill()
+		expected := fmt.Sprintf("marker '%s' in stderr", marker)
+		if errors.Is(err, KeywordNotFound) {
+			// The stderr is closed, the exit is about to be recorded.
+			<-p.Done()
+			return p.readinessError(ReadinessExited, expected, err)
 		}
-		return err
+		p.killNotReady(expected)
+		return p.readinessError(ReadinessTimeout, expected, err)
 	}
 	log.Println(p.shortName, "is running as expected")
 	return nil
 }
 
-func (p *Process) RunUntilExit() {
-	err := p.cmd.Wait()
-	// TODO: it is not clear if we should close the output streams here.
-	_ = p.stdout.Close()
-	_ = p.stderr.Close()
-	if err != nil {
-		log.Println(p.shortName, "error:", err, p.cmd.Process.Pid)
-		if errors.Is(err, context.Canceled) {
-			return
-		}
-		if strings.Contains(err.Error(), "signal: killed") {
-			return
+// RunWithMarkers is similar to RunWithMarker, but the process is ready only when all markers appear in the stderr
+// in the given order, e.g. a startup banner followed by a readiness message. The ReadinessError names the first marker
+// that was not reached.
+func (p *Process) RunWithMarkers(ctx context.Context, waitDone *sync.WaitGroup, markers []string) error {
+	if err := p.StartAsync(waitDone); err != nil {
+		return err
+	}
+	reached := 0
+	_, stderr, _ := p.outputs()
+	err := stderr.eachLine(ctx, func(line string) bool {
+		for reached < len(markers) && strings.Contains(line, markers[reached]) {
+			reached++
 		}
-		log.Fatalln("Process exited with error:", err)
+		return reached < len(markers)
+	})
+	if reached == len(markers) {
+		log.Println(p.shortName, "is running as expected")
+		return nil
+	}
+	missing := markers[reached]
+	expected := fmt.Sprintf("marker '%s' (%d of %d) in stderr", missing, reached+1, len(markers))
+	if errors.Is(err, io.EOF) {
+		// The stderr is closed, the exit is about to be recorded.
+		<-p.Done()
+		return p.readinessError(ReadinessExited, expected, fmt.Errorf("%w '%s' in output", KeywordNotFound, missing))
+	}
+	p.killNotReady(expected)
+	return p.readinessError(ReadinessTimeout, expected, err)
+}
+
+// killNotReady kills the process that did not become ready in time.
+func (p *Process) killNotReady(expected string) {
+	if err := p.KillWithCause(os.Kill, fmt.Errorf("not ready in time: %s", expected)); err != nil {
+		p.fail(err)
 	}
 }
 
-// SendSignal sends a signal to the process. It is a blocking call.
+// RunUntilExit blocks until the started process exits. It returns the exit error if the process exited
+// unexpectedly, i.e. it failed and was not killed or canceled, e.g. by the stop sequence. See Wait for the details of
+// the exit.
+func (p *Process) RunUntilExit() error {
+	return p.runUntilExit()
+}
+
+func (p *process) runUntilExit() error {
+	p.m.Lock()
+	done := p.done
+	p.m.Unlock()
+	<-done
+	p.m.Lock()
+	err, reason := p.waitErr, p.reason
+	p.m.Unlock()
+	if err == nil {
+		return nil
+	}
+	log.Println(p.shortName, "error:", err, p.osProcess().Pid)
+	switch reason {
+	case ReasonKilled, ReasonCanceled:
+		return nil
+	}
+	return fmt.Errorf("process %s exited with error: %w", p.shortName, err)
+}
+
+// FailureHandler handles a failure that has no caller to return the error to, e.g. an unexpected exit of the
+// process started with StartAsync.
+type FailureHandler func(p *Process, err error)
+
+// SetFailureHandler sets the handler of the failures that have no caller to return the error to, e.g.
+// `func(p *Process, err error) { log.Fatal(err) }` to abort on them. By default they are logged.
+func (p *Process) SetFailureHandler(h FailureHandler) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.onFailure = h
+}
+
+// fail reports err to the failure handler.
+func (p *Process) fail(err error) {
+	p.m.Lock()
+	h := p.onFailure
+	p.m.Unlock()
+	if h == nil {
+		log.Println(err)
+		return
+	}
+	h(p, err)
+}
+
+// SendSignal sends a signal to the process, it is the same as Signal.
 func (p *Process) SendSignal(s os.Signal) error {
-	log.Println("Sending signal:", s, "to process:", p.shortName, p.cmd.Process.Pid)
-	if p.cmd.Process == nil {
-		return errors.New("process is not running")
+	return p.Signal(s)
+}
+
+// Signal sends sig to the running process, e.g. syscall.SIGHUP to reload the configuration. It returns
+// ErrNotStarted if the process is not started yet and an error wrapping ErrProcessExited if it has already exited.
+// Only os.Kill is supported on Windows.
+func (p *Process) Signal(sig os.Signal) error {
+	if p.Status() == StatusNotStarted {
+		return fmt.Errorf("%w: %s", ErrNotStarted, p.shortName)
+	}
+	if p.Exited() {
+		return fmt.Errorf("%w: %s", ErrProcessExited, p.shortName)
 	}
-	if err := p.cmd.Process.Signal(s); err != nil {
-		return fmt.Errorf("failed to send signal %s to process %s: %w", s, p.shortName, err)
+	proc := p.osProcess()
+	log.Println("Sending signal:", sig, "to process:", p.shortName, proc.Pid)
+	if err := proc.Signal(sig); err != nil {
+		if errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("%w: %s", ErrProcessExited, p.shortName)
+		}
+		return fmt.Errorf("failed to send signal %s to process %s: %w", sig, p.shortName, err)
 	}
 	return nil
 }
 
-func (p *Process) Kill() {
-	log.Println("Killing process:", p.shortName, p.cmd.Process.Pid)
-	if err := p.cmd.Process.Kill(); err != nil {
-		log.Fatal(err)
+// Kill terminates the process with os.Kill, see KillWith.
+func (p *Process) Kill() error {
+	return p.KillWith(os.Kill)
+}
+
+// KillWith terminates the process with a given signal. Unlike SendSignal, it marks the process as killed, so when it
+// exits its status becomes StatusKilled and the exit is not treated as a failure. Only os.Kill is supported on
+// Windows.
+func (p *Process) KillWith(sig os.Signal) error {
+	return p.KillWithCause(sig, nil)
+}
+
+// KillWithCause is the same as KillWith, but it also records why the process is killed, e.g. a watchdog limit. The
+// cause is wrapped by the cause of ExitContext, see context.Cause.
+func (p *Process) KillWithCause(sig os.Signal, cause error) error {
+	proc := p.osProcess()
+	if proc == nil {
+		return errors.New("process is not running")
 	}
+	log.Println("Killing process:", p.shortName, proc.Pid, "with", sig)
+	p.m.Lock()
+	p.killed = true
+	if cause != nil {
+		p.killCause = cause
+	}
+	p.m.Unlock()
+	if err := p.terminate(sig); err != nil {
+		return fmt.Errorf("failed to kill process %s with %s: %w", p.shortName, sig, err)
+	}
+	return nil
 }
 
+// Status returns the current lifecycle stage of the process.
+func (p *Process) Status() Status {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.status
+}
+
+// AddEnv sets the environment variable for the process. The process inherits the environment of the current
+// process, see WithEnvFilter, and the variables set with AddEnv are added on top of it. Setting the same variable
+// again overwrites the value, unless WithStrictEnv is applied.
 func (p *Process) AddEnv(name string, value string) {
-	p.cmd.Env = append(p.cmd.Env, fmt.Sprintf("%s=%s", name, value))
+	if _, ok := p.env[name]; ok {
+		if p.strictEnv && p.envErr == nil {
+			p.envErr = fmt.Errorf("%w: %s", ErrDuplicateEnv, name)
+		}
+	} else {
+		if p.env == nil {
+			p.env = map[string]string{}
+		}
+		p.envOrder = append(p.envOrder, name)
+	}
+	p.env[name] = value
+}
+
+// String returns the command line preview of the process, with the environment variables set on the process and
+// shell quoted arguments. Values of secret environment variables are redacted.
+func (p *Process) String() string {
+	var parts []string
+	for _, kv := range p.envList() {
+		name, value, _ := strings.Cut(p.redactEnv(kv), "=")
+		parts = append(parts, name+"="+shellQuote(value))
+	}
+	for _, arg := range p.cmd.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for POSIX shell, if it is needed.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-*") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
+// IsAlive returns true if the process is started and its exit is not recorded yet.
 func (p *Process) IsAlive() bool {
-	// ProcessState populated when process exits.
-	return p.cmd != nil && p.cmd.ProcessState == nil
+	return p.Status() == StatusRunning
+}
+
+// IsPaused returns true if the process is stopped by Pause. A paused process is still alive.
+func (p *Process) IsPaused() bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.paused
 }
 
 func (p *Process) NewStdOutReader() io.ReadCloser {
-	return p.stdout.NewReader()
+	stdout, _, _ := p.outputs()
+	return stdout.NewReader()
 }
 
 func (p *Process) NewStdErrReader() io.ReadCloser {
-	return p.stderr.NewReader()
+	_, stderr, _ := p.outputs()
+	return stderr.NewReader()
 }
 
-func readLines(in io.Reader) ([]string, error) {
+// DetachOutput returns stdout and stderr accumulators of the process. They do not reference the process, so they
+// can outlive it, e.g. be passed to a reporting goroutine. Reading and scanning work as usual, and the output is
+// closed when the process exits.
+func (p *Process) DetachOutput() (*AccumulatedOutput, *AccumulatedOutput) {
+	stdout, stderr, _ := p.outputs()
+	return stdout, stderr
+}
+
+// NewCombinedReader returns a reader of stdout and stderr combined in the order of arrival.
+func (p *Process) NewCombinedReader() io.ReadCloser {
+	_, _, combined := p.outputs()
+	return combined.NewReader()
+}
+
+// CombinedBufReader starts the process, unless it is already started, and returns a bufio.Reader over stdout and
+// stderr combined in the order of arrival, see NewCombinedReader. The reader returns io.EOF after the process exits
+// and the output is read.
+func (p *Process) CombinedBufReader() (*bufio.Reader, error) {
+	if p.Status() == StatusNotStarted {
+		if err := p.Start(); err != nil {
+			return nil, err
+		}
+	}
+	return bufio.NewReader(p.NewCombinedReader()), nil
+}
+
+// Stream reads stdout of the process line by line as the lines arrive, applies transform to each line and writes
+// the result followed by a new line to dst. It returns nil when the stdout is closed.
+func (p *Process) Stream(ctx context.Context, transform func(line string) string, dst io.Writer) error {
+	var writeErr error
+	stdout, _, _ := p.outputs()
+	err := stdout.eachLine(ctx, func(line string) bool {
+		_, writeErr = io.WriteString(dst, transform(line)+"\n")
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// FailOnStderr is an opt-in check for processes that are not expected to write to stderr. The returned channel
+// receives the first stderr line as soon as it is written. The channel is closed without a value if stderr is
+// closed empty or ctx is done.
+func (p *Process) FailOnStderr(ctx context.Context) <-chan string {
+	ch := make(chan string, 1)
+	_, stderr, _ := p.outputs()
+	go func() {
+		defer close(ch)
+		_ = stderr.eachLine(ctx, func(line string) bool {
+			ch <- line
+			return false
+		})
+	}()
+	return ch
+}
+
+// RecordMarkers scans the combined output and returns the markers in the order they were first seen. It returns
+// when all markers are seen or the output is closed. If ctx is done, it returns the markers seen so far and the
+// context error.
+func (p *Process) RecordMarkers(ctx context.Context, markers ...string) ([]string, error) {
+	var order []string
+	seen := map[string]bool{}
+	_, _, combined := p.outputs()
+	err := combined.eachLine(ctx, func(line string) bool {
+		for _, m := range markers {
+			if !seen[m] && strings.Contains(line, m) {
+				seen[m] = true
+				order = append(order, m)
+			}
+		}
+		return len(order) < len(markers)
+	})
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return order, err
+}
+
+// CRMode selects how ReadStdOut and ReadStdErr handle a carriage return at the end of a line.
+type CRMode int
+
+const (
+	// StripCR drops a carriage return that precedes the new line, so "\r\n" line endings produce the same lines as
+	// "\n". It is the default and matches bufio.ScanLines.
+	StripCR CRMode = iota
+	// KeepCR keeps the carriage return as the last character of the line, for exact comparisons.
+	KeepCR
+)
+
+// SetCRMode sets how ReadStdOut and ReadStdErr handle carriage returns at the end of lines.
+func (p *Process) SetCRMode(mode CRMode) {
+	p.crMode = mode
+}
+
+// scanLinesKeepCR is bufio.ScanLines that does not drop carriage returns.
+func scanLinesKeepCR(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+func readLines(in io.Reader, mode CRMode) ([]string, error) {
 	s := bufio.NewScanner(in)
+	if mode == KeepCR {
+		s.Split(scanLinesKeepCR)
+	}
 	lines := []string{}
 	for s.Scan() {
 		lines = append(lines, s.Text())
@@ -152,11 +804,28 @@ func readLines(in io.Reader) ([]string, error) {
 }
 
 func (p *Process) ReadStdOut() ([]string, error) {
-	return readLines(p.NewStdOutReader())
+	return readLines(p.NewStdOutReader(), p.crMode)
 }
 
 func (p *Process) ReadStdErr() ([]string, error) {
-	return readLines(p.NewStdErrReader())
+	return readLines(p.NewStdErrReader(), p.crMode)
+}
+
+// FinalStdOut blocks until stdout is closed, i.e. the process exits, and returns the complete stdout as a single
+// string. Carriage returns are handled as set with SetCRMode: by default "\r\n" line endings are replaced with
+// "\n". It returns the context error if ctx is done first.
+func (p *Process) FinalStdOut(ctx context.Context) (string, error) {
+	r := &cancellableReader{reader: p.NewStdOutReader(), ctx: ctx}
+	defer r.reader.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	out := string(b)
+	if p.crMode == StripCR {
+		out = strings.ReplaceAll(out, "\r\n", "\n")
+	}
+	return out, nil
 }
 
 type OutputScanner interface {
@@ -165,9 +834,53 @@ type OutputScanner interface {
 }
 
 func (p *Process) StdOutScanner() OutputScanner {
-	return p.stdout
+	stdout, _, _ := p.outputs()
+	return stdout
 }
 
 func (p *Process) StdErrScanner() OutputScanner {
-	return p.stderr
+	_, stderr, _ := p.outputs()
+	return stderr
+}
+
+// WaitForStdOutBytes waits until stdout has accumulated at least n bytes, see AccumulatedOutput.WaitForBytes.
+func (p *Process) WaitForStdOutBytes(ctx context.Context, n int) error {
+	stdout, _, _ := p.outputs()
+	return stdout.WaitForBytes(ctx, n)
+}
+
+// WaitForStdErrBytes waits until stderr has accumulated at least n bytes, see AccumulatedOutput.WaitForBytes.
+func (p *Process) WaitForStdErrBytes(ctx context.Context, n int) error {
+	_, stderr, _ := p.outputs()
+	return stderr.WaitForBytes(ctx, n)
+}
+
+// CancelScanners aborts all pending scans of the process output, e.g. WaitForKeyword of the scanners, without
+// cancelling their contexts. The aborted scans return ErrScannersCancelled.
+func (p *Process) CancelScanners() {
+	stdout, stderr, combined := p.outputs()
+	stdout.CancelScans()
+	stderr.CancelScans()
+	combined.CancelScans()
+}
+
+// CombinedScanner returns a scanner of stdout and stderr combined in the order of arrival.
+func (p *Process) CombinedScanner() OutputScanner {
+	_, _, combined := p.outputs()
+	return combined
+}
+
+// outputs returns the outputs of the current run. Restart replaces them, so the methods that may run concurrently
+// with it read them here.
+func (p *process) outputs() (stdout, stderr, combined *AccumulatedOutput) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.stdout, p.stderr, p.combined
+}
+
+// osProcess returns the OS process of the current run, or nil if the process is not started.
+func (p *process) osProcess() *os.Process {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.proc
 }
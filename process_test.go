@@ -1,9 +1,16 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -85,3 +92,420 @@ func TestSearchWhileWorking(t *testing.T) {
 	wg.Wait()
 	log.Println("Process exits notification")
 }
+
+func TestStreamTransformsLines(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo hello && echo world")
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+
+	var dst bytes.Buffer
+	require.NoError(t, p.Stream(ctx, strings.ToUpper, &dst))
+	require.Equal(t, "HELLO\nWORLD\n", dst.String())
+	wg.Wait()
+}
+
+func TestFailOnStderr(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo fine && echo broken 1>&2 && sleep 5")
+	require.NoError(t, err)
+	failed := p.FailOnStderr(ctx)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+
+	select {
+	case line := <-failed:
+		require.Equal(t, "broken", line)
+	case <-time.After(2 * time.Second):
+		require.Fail(t, "stderr trigger did not fire")
+	}
+	p.Kill()
+	wg.Wait()
+
+	quiet, err := NewProcess(ctx, "echo", "fine")
+	require.NoError(t, err)
+	failed = quiet.FailOnStderr(ctx)
+	require.NoError(t, quiet.StartAsync(&wg))
+	wg.Wait()
+	line, ok := <-failed
+	require.False(t, ok, line)
+}
+
+func TestRecordMarkersAcrossStreams(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c",
+		"echo db-ready && sleep 0.1 && echo cache-ready 1>&2 && sleep 0.1 && echo api-ready")
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+
+	order, err := p.RecordMarkers(ctx, "api-ready", "cache-ready", "db-ready", "never")
+	require.NoError(t, err)
+	require.Equal(t, []string{"db-ready", "cache-ready", "api-ready"}, order)
+	wg.Wait()
+}
+
+func TestMarkerMatchedWhileProcessBlocks(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo marker && sleep 30")
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	defer func() {
+		p.Kill()
+		wg.Wait()
+	}()
+
+	ctx1, cancel1 := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel1()
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx1, "marker"))
+	require.True(t, p.IsAlive())
+}
+
+func TestDetachOutput(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo hello && echo world 1>&2")
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	stdout, stderr := p.DetachOutput()
+	p = nil
+	wg.Wait()
+	runtime.GC()
+
+	require.NoError(t, stdout.WaitForKeyword(ctx, "hello"))
+	cerr, err := io.ReadAll(stderr.NewReader())
+	require.NoError(t, err)
+	require.Equal(t, "world\n", string(cerr))
+}
+
+func TestReadLinesCRMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		mode     CRMode
+		expected []string
+	}{
+		{"strip crlf", "one\r\ntwo\r\n", StripCR, []string{"one", "two"}},
+		{"keep crlf", "one\r\ntwo\r\n", KeepCR, []string{"one\r", "two\r"}},
+		{"strip last line without new line", "one\r\ntwo\r", StripCR, []string{"one", "two"}},
+		{"keep last line without new line", "one\r\ntwo\r", KeepCR, []string{"one\r", "two\r"}},
+		{"keep lf", "one\ntwo", KeepCR, []string{"one", "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, err := readLines(strings.NewReader(tt.input), tt.mode)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, lines)
+		})
+	}
+}
+
+func TestStartAndConfirmAlive(t *testing.T) {
+	ctx := context.TODO()
+	var wg sync.WaitGroup
+	quitter, err := NewProcess(ctx, "bash", "-c", "echo missing config")
+	require.NoError(t, err)
+	err = quitter.StartAndConfirmAlive(&wg, time.Second)
+	require.ErrorIs(t, err, ErrExitedEarly)
+	require.Contains(t, err.Error(), "missing config")
+
+	daemon, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, daemon.StartAndConfirmAlive(&wg, 100*time.Millisecond))
+	require.True(t, daemon.IsAlive())
+	daemon.Kill()
+	wg.Wait()
+}
+
+func TestSetPrefixOnKeyword(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo starting && echo becoming worker && sleep 0.2 && echo working")
+	require.NoError(t, err)
+	var out bytes.Buffer
+	p.printer.Out = &out
+	p.SetPrefixOnKeyword("becoming worker", "worker")
+	require.NoError(t, p.Start())
+	<-p.done
+	require.Contains(t, out.String(), "bash            | starting\n")
+	require.Contains(t, out.String(), "worker          | working\n")
+	require.NotContains(t, out.String(), "bash            | working\n")
+}
+
+func TestCombinedBufReader(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo out && sleep 0.1 && echo err 1>&2 && sleep 0.1 && echo out again")
+	require.NoError(t, err)
+	r, err := p.CombinedBufReader()
+	require.NoError(t, err)
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	require.Equal(t, []string{"out\n", "err\n", "out again\n"}, lines)
+	require.NoError(t, p.WaitForExit(context.TODO()))
+}
+
+func TestResolvedPath(t *testing.T) {
+	expected, err := exec.LookPath("bash")
+	require.NoError(t, err)
+	p, err := NewProcess(context.TODO(), "bash", "-c", "true")
+	require.NoError(t, err)
+	resolved, err := p.ResolvedPath()
+	require.NoError(t, err)
+	require.True(t, filepath.IsAbs(resolved))
+	require.Equal(t, expected, resolved)
+
+	p, err = NewProcess(context.TODO(), "no-such-binary-on-path")
+	require.NoError(t, err)
+	_, err = p.ResolvedPath()
+	require.ErrorIs(t, err, exec.ErrNotFound)
+}
+
+func TestPartialLineMatch(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", "printf 'Password: ' && sleep 30")
+	require.NoError(t, err)
+	stdout, _ := p.DetachOutput()
+	stdout.SetPartialLineMatch(true)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "Password:"))
+	require.True(t, p.IsAlive())
+	require.NoError(t, p.KillWith(os.Kill))
+}
+
+func TestCancelScanners(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+
+	stdout, stderr := p.DetachOutput()
+	scans := []func() error{
+		func() error { return p.StdOutScanner().WaitForKeyword(ctx, "never") },
+		func() error { return p.CombinedScanner().WaitForKeyword(ctx, "never") },
+		func() error { return stdout.WaitForBytesPattern(ctx, []byte("never")) },
+		func() error { return stderr.WaitForKeywordPolicy(ctx, "never", ScanPolicy{Startup: time.Minute}) },
+		func() error { return stdout.ScannerAt(stdout.Checkpoint()).WaitForKeyword(ctx, "never") },
+	}
+	results := make(chan error, len(scans))
+	for _, scan := range scans {
+		go func() {
+			results <- scan()
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	p.CancelScanners()
+	for range scans {
+		require.ErrorIs(t, <-results, ErrScannersCancelled)
+	}
+
+	// The scans started later are not affected.
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, p.StdOutScanner().WaitForKeyword(timeout, "never"), context.DeadlineExceeded)
+	p.Kill()
+}
+
+func TestOnStartedHookFailureKills(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "sleep", "30")
+	require.NoError(t, err)
+	var started int
+	p.OnStarted(func(pid int) error {
+		started = pid
+		return nil
+	})
+	hookErr := errors.New("discovery is down")
+	p.OnStarted(func(int) error {
+		return hookErr
+	})
+	err = p.Start()
+	require.ErrorIs(t, err, hookErr)
+	require.Equal(t, p.cmd.Process.Pid, started)
+	require.Equal(t, StatusKilled, p.Status())
+	require.False(t, p.IsAlive())
+}
+
+func TestFinalStdOut(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", `for i in 1 2 3; do printf "line $i\r\n"; sleep 0.05; done; echo done`)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	out, err := p.FinalStdOut(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, "line 1\nline 2\nline 3\ndone\n", out)
+}
+
+func TestFinalStdOutContextDone(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo started; sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	defer p.Kill()
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	_, err = p.FinalStdOut(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStartTwice(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.ErrorIs(t, p.Start(), ErrAlreadyStarted)
+	var wg sync.WaitGroup
+	require.ErrorIs(t, p.StartAsync(&wg), ErrAlreadyStarted)
+	wg.Wait()
+	p.Kill()
+	_ = p.WaitForExit(context.TODO())
+	require.Equal(t, StatusKilled, p.Status())
+	require.ErrorIs(t, p.Start(), ErrAlreadyStarted)
+}
+
+func TestWithTempDir(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "touch created && pwd && sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithTempDir()))
+	dir := p.Dir()
+	require.DirExists(t, dir)
+	require.NoError(t, p.Start())
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, filepath.Base(dir)))
+	require.FileExists(t, filepath.Join(dir, "created"))
+
+	// The restarted process runs in a new directory.
+	require.NoError(t, p.Restart())
+	require.NoDirExists(t, dir)
+	restarted := p.Dir()
+	require.NotEqual(t, dir, restarted)
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, filepath.Base(restarted)))
+	require.FileExists(t, filepath.Join(restarted, "created"))
+	require.NoError(t, p.Kill())
+	_ = p.WaitForExit(ctx)
+	require.NoDirExists(t, restarted)
+}
+
+func TestWithTempDirChangeDirectory(t *testing.T) {
+	project := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(project, "keep"), []byte("data"), 0o644))
+	p, err := NewProcess(context.TODO(), "bash", "-c", "touch created")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithTempDir()))
+	temp := p.Dir()
+	p.ChangeDirectory(project)
+	require.NoDirExists(t, temp)
+	require.NoError(t, p.Run())
+	require.DirExists(t, project)
+	require.FileExists(t, filepath.Join(project, "keep"))
+	require.FileExists(t, filepath.Join(project, "created"))
+}
+
+func TestOnExit(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "exit 3")
+	require.NoError(t, err)
+	var calls []int
+	p.OnExit(func() { calls = append(calls, p.ExitCode()) })
+	p.OnExit(func() { calls = append(calls, 0) })
+	require.Error(t, p.Run())
+	require.Equal(t, []int{3, 0}, calls)
+}
+
+func TestWaitForStdErrBytes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `printf 'HELLO\x00\x01' >&2 && sleep 5`)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	defer p.Kill()
+	require.NoError(t, p.WaitForStdErrBytes(ctx, 7))
+	require.Equal(t, []byte("HELLO\x00\x01"), p.stderr.Snapshot())
+
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	require.ErrorIs(t, p.WaitForStdOutBytes(short, 1), context.DeadlineExceeded)
+}
+
+func TestWithBackground(t *testing.T) {
+	var printed bytes.Buffer
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo noise && echo more noise >&2 && sleep 5")
+	require.NoError(t, err)
+	p.SetOutput(&printed)
+	require.NoError(t, p.Apply(WithBackground()))
+	require.NoError(t, p.Start())
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, p.IsAlive())
+
+	p.Kill()
+	<-p.Done()
+	require.Equal(t, StatusKilled, p.Status())
+	require.Equal(t, ReasonKilled, p.ExitReason())
+	require.Zero(t, p.stdout.Checkpoint())
+	require.Zero(t, p.combined.Checkpoint())
+	require.Empty(t, printed.String())
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Empty(t, lines)
+}
+
+func TestFailureHandler(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "exit 3")
+	require.NoError(t, err)
+	var failures []error
+	p.SetFailureHandler(func(failed *Process, err error) {
+		require.Same(t, p, failed)
+		failures = append(failures, err)
+	})
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	wg.Wait()
+	require.Len(t, failures, 1)
+	var ee *ExitError
+	require.ErrorAs(t, failures[0], &ee)
+	require.Equal(t, 3, ee.ExitCode())
+	require.EqualError(t, p.RunUntilExit(), failures[0].Error())
+
+	// Killing the exited process is an error, not an abort.
+	require.ErrorIs(t, p.Kill(), os.ErrProcessDone)
+}
+
+func TestRunUntilExitKilled(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.Kill())
+	require.NoError(t, p.RunUntilExit())
+}
+
+func TestStdinPipe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `while read -r cmd; do echo "got $cmd"; done; echo eof`)
+	require.NoError(t, err)
+	stdin, err := p.StdinPipe()
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+
+	_, err = io.WriteString(stdin, "status\n")
+	require.NoError(t, err)
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "got status"))
+	require.False(t, p.Exited())
+	require.NoError(t, stdin.Close())
+	require.NoError(t, p.WaitForExit(ctx))
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"got status", "eof"}, lines)
+}
+
+func TestSetStdin(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "cat")
+	require.NoError(t, err)
+	p.SetStdin(strings.NewReader("one\ntwo\n"))
+	out, err := p.Output()
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\n", string(out))
+}
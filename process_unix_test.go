@@ -0,0 +1,287 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKillWithSigterm(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.Equal(t, StatusNotStarted, p.Status())
+
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	require.Equal(t, StatusRunning, p.Status())
+
+	require.NoError(t, p.KillWith(syscall.SIGTERM))
+	wg.Wait()
+	require.Equal(t, StatusKilled, p.Status())
+	require.False(t, p.IsAlive())
+}
+
+func TestAutoKillOnGC(t *testing.T) {
+	for name, start := range map[string]func(p *Process) error{
+		"Start": (*Process).Start,
+		"StartAsync": func(p *Process) error {
+			return p.StartAsync(&sync.WaitGroup{})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewProcess(context.TODO(), "sleep", "30")
+			require.NoError(t, err)
+			require.NoError(t, p.Apply(WithAutoKillOnGC()))
+			require.NoError(t, start(p))
+			pid := p.cmd.Process.Pid
+			p = nil
+
+			require.Eventually(t, func() bool {
+				runtime.GC()
+				return errors.Is(syscall.Kill(pid, 0), syscall.ESRCH)
+			}, 5*time.Second, 50*time.Millisecond)
+		})
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "for i in $(seq 1 1000); do echo $i; sleep 0.01; done")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "3"))
+
+	require.NoError(t, p.Pause())
+	require.True(t, p.IsPaused())
+	require.True(t, p.IsAlive())
+	// Let the output written before the stop settle.
+	time.Sleep(50 * time.Millisecond)
+	stalled := p.stdout.Checkpoint()
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, stalled, p.stdout.Checkpoint())
+
+	require.NoError(t, p.Resume())
+	require.False(t, p.IsPaused())
+	require.Eventually(t, func() bool {
+		return p.stdout.Checkpoint() > stalled
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, p.KillWith(syscall.SIGKILL))
+	require.Error(t, p.WaitForExit(ctx))
+	require.Error(t, p.Pause())
+}
+
+func TestStopSequenceGraceful(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.TODO())
+	defer cancel(nil)
+	p, err := NewProcess(ctx, "bash", "-c", `trap 'echo got TERM; exit 0' TERM; echo started; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	p.SetStopSequence(syscall.SIGTERM, 5*time.Second)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "started"))
+
+	start := time.Now()
+	cancel(errors.New("test finished"))
+	<-p.Done()
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Contains(t, string(p.stdout.Snapshot()), "got TERM")
+	require.Equal(t, ReasonCanceled, p.ExitReason())
+	require.EqualError(t, context.Cause(p.ExitContext()), "process exited: bash was canceled: test finished")
+}
+
+func TestStopSequenceKillsAfterGrace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `trap 'echo ignored TERM' TERM; echo started; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	p.SetStopSequence(syscall.SIGTERM, 200*time.Millisecond)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "started"))
+
+	start := time.Now()
+	cancel()
+	<-p.Done()
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Contains(t, string(p.stdout.Snapshot()), "ignored TERM")
+	require.Equal(t, ReasonCanceled, p.ExitReason())
+	require.Equal(t, -1, p.ExitCode())
+	require.ErrorIs(t, context.Cause(p.ExitContext()), context.Canceled)
+}
+
+func TestStopSequenceIsNotFailure(t *testing.T) {
+	for name, script := range map[string]string{
+		"exit code": `trap 'exit 143' TERM; echo started; while true; do sleep 0.05; done`,
+		"signal":    `echo started; exec sleep 5`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			p, err := NewProcess(ctx, "bash", "-c", script)
+			require.NoError(t, err)
+			p.SetStopSequence(syscall.SIGTERM, 5*time.Second)
+			var failures []error
+			p.SetFailureHandler(func(_ *Process, err error) { failures = append(failures, err) })
+			var wg sync.WaitGroup
+			require.NoError(t, p.StartAsync(&wg))
+			require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "started"))
+
+			cancel()
+			wg.Wait()
+			require.Equal(t, ReasonCanceled, p.ExitReason())
+			require.Error(t, p.ExitError())
+			require.Empty(t, failures)
+			require.NoError(t, p.RunUntilExit())
+		})
+	}
+}
+
+func TestStartWithDoneContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	p, err := NewProcess(ctx, "sleep", "5")
+	require.NoError(t, err)
+	require.ErrorIs(t, p.Start(), context.Canceled)
+	require.Equal(t, StatusNotStarted, p.Status())
+}
+
+func TestStop(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", `trap 'echo flushed; exit 0' TERM; echo started; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "started"))
+	require.NoError(t, p.Stop(context.TODO()))
+	require.Contains(t, string(p.stdout.Snapshot()), "flushed")
+	require.Equal(t, StatusKilled, p.Status())
+	require.Equal(t, 0, p.ExitCode())
+}
+
+func TestStopEscalates(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", `trap 'echo ignored' TERM; echo started; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	p.SetStopSequence(syscall.SIGTERM, 200*time.Millisecond)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "started"))
+	start := time.Now()
+	require.NoError(t, p.Stop(context.TODO()))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, -1, p.ExitCode())
+	require.Equal(t, ReasonKilled, p.ExitReason())
+
+	// The context cuts the grace period short.
+	p, err = NewProcess(context.TODO(), "bash", "-c", `trap 'echo ignored' TERM; echo started; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "started"))
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	require.ErrorIs(t, p.Stop(ctx), context.DeadlineExceeded)
+	require.Less(t, time.Since(start), DefaultStopGrace)
+	require.Equal(t, StatusKilled, p.Status())
+
+	p, err = NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	require.ErrorIs(t, p.Stop(context.TODO()), ErrNotStarted)
+}
+
+func TestStopEscalatesToProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	// The leader exits on SIGTERM, the child ignores it.
+	p, err := NewProcess(ctx, "bash", "-c", "(trap '' TERM; exec sleep 30) >/dev/null 2>&1 & echo $!; wait")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithProcessGroup()))
+	p.SetStopSequence(syscall.SIGTERM, 300*time.Millisecond)
+	require.NoError(t, p.Start())
+	line, err := p.StdOutScanner().(*AccumulatedOutput).LineAt(ctx, 0)
+	require.NoError(t, err)
+	child, err := strconv.Atoi(line)
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, p.Stop(ctx))
+	require.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return errors.Is(syscall.Kill(child, 0), syscall.ESRCH)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWaitExitStatus(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo failing >&2 && exit 3")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	status, err := p.Wait(context.TODO())
+	require.Equal(t, ExitStatus{Code: 3, Reason: ReasonFailure}, status)
+	var ee *exec.ExitError
+	require.ErrorAs(t, err, &ee)
+	require.Equal(t, 3, ee.ExitCode())
+
+	p, err = NewProcess(context.TODO(), "bash", "-c", "kill -TERM $$")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	status, err = p.Wait(context.TODO())
+	require.Error(t, err)
+	require.Equal(t, ExitStatus{Code: -1, Reason: ReasonSignaled, Signal: syscall.SIGTERM}, status)
+
+	p, err = NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+	_, err = p.Wait(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	p.Kill()
+	status, err = p.Wait(context.TODO())
+	require.ErrorAs(t, err, &ee)
+	require.Equal(t, ReasonKilled, status.Reason)
+	require.Equal(t, os.Kill, status.Signal)
+}
+
+func TestSignal(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", `trap 'echo reloaded' HUP; echo started; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	require.ErrorIs(t, p.Signal(syscall.SIGHUP), ErrNotStarted)
+	require.NoError(t, p.Start())
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "started"))
+
+	require.NoError(t, p.Signal(syscall.SIGHUP))
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "reloaded"))
+	require.True(t, p.IsAlive())
+
+	require.NoError(t, p.Kill())
+	<-p.Done()
+	require.ErrorIs(t, p.Signal(syscall.SIGHUP), ErrProcessExited)
+}
+
+func TestWithProcessGroupKillsChildren(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", "sleep 30 >/dev/null 2>&1 & echo $!; wait")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithProcessGroup()))
+	require.NoError(t, p.Start())
+	line, err := p.StdOutScanner().(*AccumulatedOutput).LineAt(ctx, 0)
+	require.NoError(t, err)
+	child, err := strconv.Atoi(line)
+	require.NoError(t, err)
+	require.NoError(t, syscall.Kill(child, 0))
+
+	require.NoError(t, p.Kill())
+	_, _ = p.Wait(ctx)
+	require.Eventually(t, func() bool {
+		return errors.Is(syscall.Kill(child, 0), syscall.ESRCH)
+	}, 2*time.Second, 10*time.Millisecond)
+}
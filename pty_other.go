@@ -0,0 +1,30 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"os"
+)
+
+// WithPTY is not supported on this platform.
+func WithPTY() Option {
+	return func(*Process) error {
+		return errors.ErrUnsupported
+	}
+}
+
+// PTY is not supported on this platform.
+func (p *Process) PTY() *os.File {
+	return nil
+}
+
+// SetWindowSize is not supported on this platform.
+func (p *Process) SetWindowSize(uint16, uint16) error {
+	return errors.ErrUnsupported
+}
+
+// ptyPipes is not supported on this platform.
+func ptyPipes(*process) (func(), <-chan struct{}, error) {
+	return nil, nil, errors.ErrUnsupported
+}
@@ -0,0 +1,95 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// WithPTY runs the process under a pseudo-terminal, for programs that change their behavior or buffer the output
+// when it is not a terminal. The terminal output is accumulated as stdout, stderr stays empty because the terminal
+// merges both. The input can be written to the terminal, see PTY, and its size set with SetWindowSize. Carriage
+// returns of the terminal line endings are stripped by ReadStdOut, see SetCRMode.
+func WithPTY() Option {
+	return func(p *Process) error {
+		p.ptyMode = true
+		return nil
+	}
+}
+
+// PTY returns the controlling side of the pseudo-terminal of the process started with WithPTY, to write the input,
+// or nil if the process is not started. The output is read by the process, it should not be read from the terminal.
+func (p *Process) PTY() *os.File {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.ptmx
+}
+
+// SetWindowSize sets the size of the pseudo-terminal of the process started with WithPTY. Called before the process
+// is started, it sets the initial size. The process gets SIGWINCH when the size changes.
+func (p *Process) SetWindowSize(rows, cols uint16) error {
+	if !p.ptyMode {
+		return errors.New("process does not run under a pseudo-terminal, see WithPTY")
+	}
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.ptyRows, p.ptyCols = rows, cols
+	if p.ptmx == nil {
+		return nil
+	}
+	return pty.Setsize(p.ptmx, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+// ptyPipes opens the pseudo-terminal and makes it the controlling terminal and the standard streams of the command.
+// The returned function should be called after the command is started. The returned channel is closed when the
+// output of the terminal is drained, i.e. every process that inherited the terminal closed it.
+func ptyPipes(p *process) (func(), <-chan struct{}, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.m.Lock()
+	if p.ptyRows > 0 || p.ptyCols > 0 {
+		err = pty.Setsize(ptmx, &pty.Winsize{Rows: p.ptyRows, Cols: p.ptyCols})
+	}
+	p.ptmx = ptmx
+	p.m.Unlock()
+	if err != nil {
+		_ = ptmx.Close()
+		_ = tty.Close()
+		return nil, nil, err
+	}
+	stdout := p.cmd.Stdout
+	if p.cmd.Stdin == nil {
+		p.cmd.Stdin = tty
+		p.stdinPipe = true
+	}
+	p.cmd.Stdout = tty
+	p.cmd.Stderr = tty
+	if p.cmd.SysProcAttr == nil {
+		p.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// The new session is also a new process group, setting it explicitly fails.
+	p.cmd.SysProcAttr.Setpgid = false
+	p.cmd.SysProcAttr.Setsid = true
+	p.cmd.SysProcAttr.Setctty = true
+	p.abortDrain = func() {
+		_ = ptmx.Close()
+	}
+	drained := make(chan struct{})
+	return func() {
+		// The child has its own copy of the terminal.
+		_ = tty.Close()
+		go func() {
+			defer close(drained)
+			defer ptmx.Close()
+			// Reading the terminal fails with EIO once it is closed by all processes, it means EOF.
+			_, _ = io.Copy(stdout, ptmx)
+		}()
+	}, drained, nil
+}
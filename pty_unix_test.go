@@ -0,0 +1,45 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPTY(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `[ -t 1 ] && echo terminal; stty size; read -r name; echo "hello $name"`)
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithPTY()))
+	require.NoError(t, p.SetWindowSize(40, 100))
+	require.NoError(t, p.Start())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "40 100"))
+
+	_, err = io.WriteString(p.PTY(), "runner\n")
+	require.NoError(t, err)
+	require.NoError(t, p.WaitForExit(ctx))
+	lines, err := p.ReadStdOut()
+	require.NoError(t, err)
+	// The terminal echoes the input.
+	require.Equal(t, []string{"terminal", "40 100", "runner", "hello runner"}, lines)
+	require.Empty(t, p.stderr.Snapshot())
+}
+
+func TestWithPTYResize(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `trap 'stty size' WINCH; echo ready; while true; do sleep 0.05; done`)
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithPTY()))
+	require.NoError(t, p.Start())
+	defer p.Kill()
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "ready"))
+	require.NoError(t, p.SetWindowSize(24, 80))
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "24 80"))
+}
@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateMeter counts the lines of the output in background and reports the rate over a trailing window.
+type RateMeter struct {
+	window time.Duration
+	m      sync.Mutex
+	// times are the arrival times of the lines within the window.
+	times []time.Time
+}
+
+// RateMeter starts counting the lines of the output, including the lines accumulated so far, until ctx is done or
+// the output is closed. See RateMeter.Current.
+func (s *AccumulatedOutput) RateMeter(ctx context.Context, window time.Duration) *RateMeter {
+	r := &RateMeter{window: window}
+	go func() {
+		_ = s.eachLine(ctx, func(string) bool {
+			r.add(time.Now())
+			return true
+		})
+	}()
+	return r
+}
+
+func (r *RateMeter) add(t time.Time) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.times = append(r.times, t)
+	r.prune(t)
+}
+
+// prune drops the times that are out of the window ending at now. It should be called with the lock held.
+func (r *RateMeter) prune(now time.Time) {
+	i := 0
+	for i < len(r.times) && now.Sub(r.times[i]) > r.window {
+		i++
+	}
+	r.times = r.times[i:]
+}
+
+// Current returns the number of lines per second over the trailing window.
+func (r *RateMeter) Current() float64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.prune(time.Now())
+	return float64(len(r.times)) / r.window.Seconds()
+}
@@ -0,0 +1,40 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateMeter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	out := NewAccumulatedOutput(io.Discard)
+	meter := out.RateMeter(ctx, 500*time.Millisecond)
+	require.Zero(t, meter.Current())
+
+	// 100 lines per second.
+	stop := make(chan struct{})
+	go func() {
+		t := time.NewTicker(10 * time.Millisecond)
+		defer t.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				_, _ = fmt.Fprintf(out, "line %d\n", i)
+			}
+		}
+	}()
+	time.Sleep(time.Second)
+	require.InDelta(t, 100, meter.Current(), 30)
+
+	close(stop)
+	time.Sleep(600 * time.Millisecond)
+	require.Zero(t, meter.Current())
+}
@@ -0,0 +1,184 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReadinessKind classifies why the process did not become ready.
+type ReadinessKind string
+
+const (
+	// ReadinessExited means the process exited before it became ready.
+	ReadinessExited ReadinessKind = "exited"
+	// ReadinessTimeout means the process is alive, but the marker did not appear in time.
+	ReadinessTimeout ReadinessKind = "timeout"
+	// ReadinessPortClosed means the process is alive, but the port was not opened in time.
+	ReadinessPortClosed ReadinessKind = "port closed"
+	// ReadinessUnhealthy means the process is alive, but the health check did not succeed in time.
+	ReadinessUnhealthy ReadinessKind = "unhealthy"
+	// ReadinessFileMissing means the process is alive, but the file was not created in time.
+	ReadinessFileMissing ReadinessKind = "file missing"
+	// ReadinessProbeFailed means the process is alive, but the readiness probe failed, see StartAndWaitReady.
+	ReadinessProbeFailed ReadinessKind = "probe failed"
+)
+
+// readinessPollInterval is the interval between the attempts of WaitForPort, WaitForHealthy and WaitForFile.
+const readinessPollInterval = 50 * time.Millisecond
+
+// ReadinessError is returned by the readiness helpers, e.g. RunWithMarker, when the process does not become ready.
+// It describes the failure with the exit info and the output tail.
+type ReadinessError struct {
+	Kind ReadinessKind
+	// Name is the short name of the process.
+	Name string
+	// Expected describes the readiness condition, e.g. the marker.
+	Expected string
+	// ExitCode and Reason describe the exit, if the process exited.
+	ExitCode int
+	Reason   ExitReason
+	// Tail is the last lines of the combined output.
+	Tail []string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ReadinessError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s is not ready (%s): %s: %v", e.Name, e.Kind, e.Expected, e.Err)
+	if e.Reason != ReasonNone {
+		fmt.Fprintf(&b, ", exited with code %d (%s)", e.ExitCode, e.Reason)
+	}
+	if len(e.Tail) > 0 {
+		fmt.Fprintf(&b, ", output:\n%s", strings.Join(e.Tail, "\n"))
+	}
+	return b.String()
+}
+
+func (e *ReadinessError) Unwrap() error {
+	return e.Err
+}
+
+// readinessError returns ReadinessError with the current exit info and output tail of the process.
+func (p *Process) readinessError(kind ReadinessKind, expected string, err error) *ReadinessError {
+	summary := p.Summary()
+	return &ReadinessError{
+		Kind:     kind,
+		Name:     p.shortName,
+		Expected: expected,
+		ExitCode: summary.ExitCode,
+		Reason:   summary.Reason,
+		Tail:     summary.Tail,
+		Err:      err,
+	}
+}
+
+// WaitForPort waits until a TCP connection to addr succeeds. It returns ReadinessError of ReadinessExited kind if
+// the process exits first, or of ReadinessPortClosed kind if ctx is done first.
+func (p *Process) WaitForPort(ctx context.Context, addr string) error {
+	expected := fmt.Sprintf("port %s is open", addr)
+	var d net.Dialer
+	return p.poll(ctx, ReadinessPortClosed, expected, func() error {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// WaitForHealthy waits until GET url responds with 200 OK. It returns ReadinessError of ReadinessExited kind if the
+// process exits first, or of ReadinessUnhealthy kind if ctx is done first.
+func (p *Process) WaitForHealthy(ctx context.Context, url string) error {
+	expected := fmt.Sprintf("GET %s returns 200", url)
+	return p.poll(ctx, ReadinessUnhealthy, expected, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %s", resp.Status)
+		}
+		return nil
+	})
+}
+
+// WaitForFile waits until the file at path exists, e.g. a socket or a pid file created by the process. It returns
+// ReadinessError of ReadinessExited kind if the process exits first, or of ReadinessFileMissing kind if ctx is done
+// first.
+func (p *Process) WaitForFile(ctx context.Context, path string) error {
+	expected := fmt.Sprintf("file %s exists", path)
+	return p.poll(ctx, ReadinessFileMissing, expected, func() error {
+		_, err := os.Stat(path)
+		return err
+	})
+}
+
+// poll calls check until it succeeds. It fails with kind when ctx is done, the error includes the last check error.
+func (p *Process) poll(ctx context.Context, kind ReadinessKind, expected string, check func() error) error {
+	t := time.NewTicker(readinessPollInterval)
+	defer t.Stop()
+	done := p.Done()
+	var last error
+	for {
+		err := check()
+		if err == nil {
+			log.Println(p.shortName, "is ready:", expected)
+			return nil
+		}
+		if ctx.Err() == nil {
+			// The check aborted by ctx is not informative.
+			last = err
+		}
+		select {
+		case <-done:
+			return p.readinessError(ReadinessExited, expected, ErrExitedEarly)
+		case <-ctx.Done():
+			return p.readinessError(kind, expected, errors.Join(ctx.Err(), last))
+		case <-t.C:
+		}
+	}
+}
+
+// WaitForKeywordOrExit waits until substr appears in stdout or stderr, or the process exits, whichever happens
+// first. It returns nil when substr is found, ReadinessError of ReadinessExited kind with the exit code if the process
+// exits without printing it, or the context error.
+func (p *Process) WaitForKeywordOrExit(ctx context.Context, substr string) error {
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, _, combined := p.outputs()
+	done := p.Done()
+	found := make(chan error, 1)
+	go func() {
+		found <- combined.WaitForKeyword(scanCtx, substr)
+	}()
+	expected := fmt.Sprintf("keyword '%s' in output", substr)
+	select {
+	case err := <-found:
+		if !errors.Is(err, KeywordNotFound) {
+			return err
+		}
+		// The output is closed, the exit is about to be recorded.
+		<-done
+	case <-done:
+		// The keyword may be in the output that is not scanned yet.
+		if strings.Contains(string(combined.Snapshot()), substr) {
+			return nil
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.readinessError(ReadinessExited, expected, ErrExitedEarly)
+}
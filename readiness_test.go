@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireReadinessKind(t *testing.T, err error, kind ReadinessKind) *ReadinessError {
+	t.Helper()
+	var re *ReadinessError
+	require.True(t, errors.As(err, &re), "expected ReadinessError, got %v", err)
+	require.Equal(t, kind, re.Kind)
+	return re
+}
+
+func TestRunWithMarkerReadiness(t *testing.T) {
+	var wg sync.WaitGroup
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo missing config 1>&2")
+	require.NoError(t, err)
+	err = p.RunWithMarker(context.TODO(), &wg, "ready")
+	re := requireReadinessKind(t, err, ReadinessExited)
+	require.ErrorIs(t, err, KeywordNotFound)
+	require.Equal(t, ReasonSuccess, re.Reason)
+	require.Contains(t, err.Error(), "missing config")
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	p, err = NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	err = p.RunWithMarker(ctx, &wg, "ready")
+	requireReadinessKind(t, err, ReadinessTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	wg.Wait()
+}
+
+func TestWaitForPortReadiness(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	open := l.Addr().String()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	require.NoError(t, closed.Close())
+	defer l.Close()
+
+	p, err := NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForPort(context.TODO(), open))
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+	requireReadinessKind(t, p.WaitForPort(ctx, closedAddr), ReadinessPortClosed)
+	p.Kill()
+
+	p, err = NewProcess(context.TODO(), "bash", "-c", "sleep 0.1")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	err = p.WaitForPort(context.TODO(), closedAddr)
+	requireReadinessKind(t, err, ReadinessExited)
+	require.ErrorIs(t, err, ErrExitedEarly)
+}
+
+func TestWaitForHealthyReadiness(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	p, err := NewProcess(context.TODO(), "sleep", "5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForHealthy(context.TODO(), healthy.URL))
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+	err = p.WaitForHealthy(ctx, unhealthy.URL)
+	requireReadinessKind(t, err, ReadinessUnhealthy)
+	require.ErrorContains(t, err, "503")
+	p.Kill()
+}
+
+func TestWaitForKeywordOrExit(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo loading && echo bad config 1>&2 && exit 3")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	err = p.WaitForKeywordOrExit(ctx, "ready")
+	require.ErrorIs(t, err, ErrExitedEarly)
+	re := requireReadinessKind(t, err, ReadinessExited)
+	require.Equal(t, 3, re.ExitCode)
+	require.Contains(t, err.Error(), "bad config")
+
+	p, err = NewProcess(ctx, "bash", "-c", "echo ready 1>&2 && sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForKeywordOrExit(ctx, "ready"))
+
+	timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, p.WaitForKeywordOrExit(timeout, "never"), context.DeadlineExceeded)
+	p.Kill()
+}
+
+func TestRunWithMarkers(t *testing.T) {
+	var wg sync.WaitGroup
+	markers := []string{"banner", "ready"}
+	ctx, cancel := context.WithTimeout(context.TODO(), 300*time.Millisecond)
+	defer cancel()
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo ready too early && echo banner 1>&2 && sleep 5")
+	require.NoError(t, err)
+	err = p.RunWithMarkers(ctx, &wg, markers)
+	requireReadinessKind(t, err, ReadinessTimeout)
+	require.ErrorContains(t, err, "marker 'ready' (2 of 2)")
+	wg.Wait()
+
+	p, err = NewProcess(context.TODO(), "bash", "-c", "echo banner 1>&2 && sleep 0.1 && echo ready 1>&2 && sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.RunWithMarkers(context.TODO(), &wg, markers))
+	p.Kill()
+	wg.Wait()
+}
+
+func TestWaitForFileReadiness(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	p, err := NewProcess(context.TODO(), "bash", "-c", "sleep 0.1 && touch "+path+" && sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForFile(context.TODO(), path))
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+	requireReadinessKind(t, p.WaitForFile(ctx, path+".missing"), ReadinessFileMissing)
+	p.Kill()
+}
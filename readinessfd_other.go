@@ -0,0 +1,28 @@
+//go:build !unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+)
+
+// ReadinessFDEnv is the environment variable with the number of the file descriptor set up by WithReadinessFD.
+const ReadinessFDEnv = "READINESS_FD"
+
+// WithReadinessFD is not supported on this platform.
+func WithReadinessFD() Option {
+	return func(*Process) error {
+		return errors.ErrUnsupported
+	}
+}
+
+// WaitForReadinessFD is not supported on this platform.
+func (p *Process) WaitForReadinessFD(context.Context) error {
+	return errors.ErrUnsupported
+}
+
+// openReadinessFD does nothing, WithReadinessFD is not supported on this platform.
+func (p *process) openReadinessFD() (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,95 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ReadinessFDEnv is the environment variable with the number of the file descriptor set up by WithReadinessFD.
+const ReadinessFDEnv = "READINESS_FD"
+
+// WithReadinessFD passes the write end of a pipe to the process as an extra file descriptor, its number is set in
+// READINESS_FD environment variable, e.g. READINESS_FD=3. The process signals that it is ready by writing anything to
+// it, see WaitForReadinessFD. It is an alternative to log markers, similar to sd_notify. A new pipe is created on
+// every start, so it works with Restart.
+func WithReadinessFD() Option {
+	return func(p *Process) error {
+		// The slot is filled by Start. The extra files follow stdin, stdout and stderr.
+		p.cmd.ExtraFiles = append(p.cmd.ExtraFiles, nil)
+		p.readinessSlot = len(p.cmd.ExtraFiles)
+		p.AddEnv(ReadinessFDEnv, strconv.Itoa(2+p.readinessSlot))
+		return nil
+	}
+}
+
+// openReadinessFD creates the readiness pipe for the next run, if WithReadinessFD is applied. The returned function
+// closes the write end, it should be called after the command is started.
+func (p *process) openReadinessFD() (func(), error) {
+	if p.readinessSlot == 0 {
+		return func() {}, nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create readiness pipe for %s: %w", p.shortName, err)
+	}
+	p.cmd.ExtraFiles[p.readinessSlot-1] = w
+	p.m.Lock()
+	old := p.readinessFD
+	p.readinessFD = r
+	p.m.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	return func() {
+		// The child has its own copy, closing ours makes the pipe report EOF when the child closes it.
+		_ = w.Close()
+	}, nil
+}
+
+// WaitForReadinessFD waits until the process writes to the readiness file descriptor set up with WithReadinessFD.
+// It returns ReadinessError of ReadinessExited kind if the process exits first, or of ReadinessTimeout kind if ctx is
+// done first.
+func (p *Process) WaitForReadinessFD(ctx context.Context) error {
+	if p.readinessSlot == 0 {
+		return fmt.Errorf("readiness file descriptor of %s is not set up, see WithReadinessFD", p.shortName)
+	}
+	p.m.Lock()
+	fd := p.readinessFD
+	p.m.Unlock()
+	if fd == nil {
+		return fmt.Errorf("%w: %s", ErrNotStarted, p.shortName)
+	}
+	const expected = "write to readiness file descriptor"
+	if err := fd.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = fd.SetReadDeadline(time.Now())
+	})
+	defer stop()
+	n, err := fd.Read(make([]byte, 1))
+	switch {
+	case n > 0:
+		log.Println(p.shortName, "is ready:", expected)
+		return nil
+	case ctx.Err() != nil:
+		return p.readinessError(ReadinessTimeout, expected, ctx.Err())
+	case !errors.Is(err, io.EOF):
+		return err
+	}
+	// The process closed the descriptor without writing to it, it is not going to become ready.
+	select {
+	case <-p.Done():
+		return p.readinessError(ReadinessExited, expected, ErrExitedEarly)
+	case <-ctx.Done():
+		return p.readinessError(ReadinessTimeout, expected, ctx.Err())
+	}
+}
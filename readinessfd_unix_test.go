@@ -0,0 +1,61 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForReadinessFD(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `sleep 0.1 && echo ready >&$READINESS_FD && sleep 5`)
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithReadinessFD()))
+	require.NoError(t, p.Start())
+	defer p.Kill()
+	require.NoError(t, p.WaitForReadinessFD(ctx))
+	require.True(t, p.IsAlive())
+}
+
+func TestWaitForReadinessFDExited(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", "echo starting && exit 1")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithReadinessFD()))
+	require.NoError(t, p.Start())
+	requireReadinessKind(t, p.WaitForReadinessFD(ctx), ReadinessExited)
+}
+
+func TestWaitForReadinessFDTimeout(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "sleep 5")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithReadinessFD()))
+	require.NoError(t, p.Start())
+	defer p.Kill()
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	requireReadinessKind(t, p.WaitForReadinessFD(ctx), ReadinessTimeout)
+}
+
+func TestWaitForReadinessFDRestart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `sleep 0.1 && echo ready >&$READINESS_FD && sleep 5`)
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithReadinessFD()))
+	require.ErrorIs(t, p.WaitForReadinessFD(ctx), ErrNotStarted)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForReadinessFD(ctx))
+
+	// The restarted process gets a new pipe.
+	require.NoError(t, p.Restart())
+	defer p.Kill()
+	require.NoError(t, p.WaitForReadinessFD(ctx))
+	require.False(t, p.Exited())
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// Restart stops the process if it is running, and starts it again with the same command, environment and settings.
+// The output of the previous run is discarded and the exit state, i.e. exit code, reason, timestamps and exit
+// error, is reset before the new run starts. The methods that read the output or wait for the exit can be called
+// concurrently, e.g. while Supervise restarts the process, but they see the run that was current when they were
+// called. Restart itself should not be called concurrently with the methods that configure the process.
+func (p *Process) Restart() error {
+	status := p.Status()
+	if status == StatusRunning {
+		if err := p.KillWith(os.Kill); err != nil {
+			return err
+		}
+	}
+	if status != StatusNotStarted {
+		// The exit hooks run after the status is recorded.
+		<-p.Done()
+	}
+	p.reset()
+	if p.tempDir != "" {
+		if err := p.newTempDir(); err != nil {
+			return err
+		}
+	}
+	return p.Start()
+}
+
+// reset rebuilds the command and the outputs and returns the process to the not started state.
+func (p *process) reset() {
+	old := p.cmd
+	cmd := newCommand(old.Args[0], old.Args[1:]...)
+	cmd.Path = old.Path
+	cmd.Dir = old.Dir
+	if !p.stdinPipe {
+		// The pipe is closed when the process exits, it is not reusable.
+		cmd.Stdin = old.Stdin
+	}
+	p.stdinPipe = false
+	cmd.SysProcAttr = old.SysProcAttr
+	// The readiness pipe in ExtraFiles is replaced on start.
+	cmd.ExtraFiles = old.ExtraFiles
+	cmd.WaitDelay = old.WaitDelay
+
+	// Keep the mirrors and settings, they may be changed, e.g. by SetBinaryOutput.
+	stdout, stderr, combined := renewOutput(p.stdout), renewOutput(p.stderr), renewOutput(p.combined)
+	cmd.Stdout = io.MultiWriter(stdout, combined)
+	cmd.Stderr = io.MultiWriter(stderr, combined)
+	tagged := p.tagged
+	if p.background {
+		cmd.Stdout, cmd.Stderr = nil, nil
+	} else if tagged != nil {
+		tagged = newTaggedOutput(cmd.Stdout, cmd.Stderr)
+	}
+
+	// The readers of the previous run may still read the fields, see Restart.
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.cmd = cmd
+	p.proc = nil
+	p.descendants = nil
+	p.stdout, p.stderr, p.combined = stdout, stderr, combined
+	p.tagged = tagged
+	p.done = make(chan struct{})
+	p.exitCtx, p.exitCancel = context.WithCancelCause(context.Background())
+	p.waitErr = nil
+	p.status = StatusNotStarted
+	p.killed = false
+	p.killCause = nil
+	p.stopped = false
+	p.paused = false
+	p.startedAt = time.Time{}
+	p.exitedAt = time.Time{}
+	p.exitCode = -1
+	p.reason = ReasonNone
+}
+
+// renewOutput returns an empty output with the buffer options, clock, timed lines and settings of o.
+func renewOutput(o *AccumulatedOutput) *AccumulatedOutput {
+	n := NewAccumulatedOutput(o.out, o.opts...)
+	o.lm.Lock()
+	n.clock = o.clock
+	n.timed = o.timed
+	o.lm.Unlock()
+	n.readChunk = o.readChunk
+	n.partialMatch = o.partialMatch
+	return n
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartResetsExitState(t *testing.T) {
+	ctx := context.TODO()
+	// The first run fails and leaves a marker file, the second run succeeds.
+	marker := filepath.Join(t.TempDir(), "ran")
+	p, err := NewProcess(ctx, "bash", "-c", "echo run; if [ -f "+marker+" ]; then exit 0; fi; touch "+marker+"; exit 3")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.Error(t, p.WaitForExit(ctx))
+	require.Equal(t, 3, p.ExitCode())
+	require.Equal(t, ReasonFailure, p.ExitReason())
+
+	require.NoError(t, p.Restart())
+	require.NoError(t, p.WaitForExit(ctx))
+	require.NoError(t, p.ExitError())
+	require.Equal(t, 0, p.ExitCode())
+	require.Equal(t, ReasonSuccess, p.ExitReason())
+	require.Equal(t, StatusExited, p.Status())
+	stdout, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"run"}, stdout)
+}
+
+func TestRestartStopsRunningProcess(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "sleep", "30")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.Restart())
+	require.Equal(t, StatusRunning, p.Status())
+	require.Equal(t, -1, p.ExitCode())
+	require.Equal(t, ReasonNone, p.ExitReason())
+	require.NoError(t, p.KillWith(os.Kill))
+	require.Error(t, p.WaitForExit(ctx))
+	require.Equal(t, StatusKilled, p.Status())
+}
+
+func TestRestartKeepsOutputSettings(t *testing.T) {
+	ctx := context.TODO()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	p, err := NewProcess(ctx, "bash", "-c", "echo one && echo two && echo three")
+	require.NoError(t, err)
+	p.stdout = NewAccumulatedOutput(io.Discard, WithMaxLines(2))
+	p.stdout.SetClock(clock)
+	p.stdout.SetTimedLines(true)
+	p.stdout.SetPartialLineMatch(true)
+
+	require.NoError(t, p.Restart())
+	require.NoError(t, p.WaitForExit(ctx))
+	require.Equal(t, "two\nthree\n", string(p.stdout.Snapshot()))
+	require.True(t, p.stdout.partialMatch)
+	lines := p.stdout.TimedLines()
+	require.Len(t, lines, 3)
+	for _, l := range lines {
+		require.Equal(t, clock.now, l.Time)
+	}
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrRSSExceeded is returned by WatchRSS when the resident memory of the process exceeds the limit.
+var ErrRSSExceeded = errors.New("process memory limit exceeded")
+
+// WatchRSS samples resident memory size (RSS) of the process right away and then every interval, and kills the process as soon as it
+// exceeds max bytes, returning ErrRSSExceeded. It returns nil when the process exits, or the context error. It is
+// supported only on Linux, elsewhere it is a no-op that returns nil immediately.
+func (p *Process) WatchRSS(ctx context.Context, interval time.Duration, max uint64) error {
+	proc := p.osProcess()
+	if proc == nil {
+		return errors.New("process is not running")
+	}
+	pid := proc.Pid
+	done := p.Done()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	// The first sample is taken right away, so it returns immediately where it is not supported.
+	for {
+		rss, err := readRSS(pid)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil
+		}
+		if err != nil {
+			if !p.IsAlive() {
+				return nil
+			}
+			return fmt.Errorf("failed to read memory usage of %s: %w", p.shortName, err)
+		}
+		if rss > max {
+			exceeded := fmt.Errorf("%w: %s uses %d bytes, limit is %d", ErrRSSExceeded, p.shortName, rss, max)
+			if err := p.KillWithCause(os.Kill, exceeded); err != nil {
+				return err
+			}
+			return exceeded
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-done:
+			return nil
+		case <-t.C:
+		}
+	}
+}
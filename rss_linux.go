@@ -0,0 +1,28 @@
+//go:build linux
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readRSS returns resident memory size of the process in bytes.
+func readRSS(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	// Second field is resident set size in pages.
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format: %q", data)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,43 @@
+//go:build linux
+
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchRSSKillsGrowingProcess(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	// Doubles the string on every iteration.
+	p, err := NewProcess(ctx, "bash", "-c", `x=a; while true; do x="$x$x"; sleep 0.05; done`)
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+
+	err = p.WatchRSS(ctx, 20*time.Millisecond, 32<<20)
+	require.ErrorIs(t, err, ErrRSSExceeded)
+	wg.Wait()
+	require.Equal(t, StatusKilled, p.Status())
+}
+
+func TestWatchRSSKillCause(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	p, err := NewProcess(ctx, "bash", "-c", `x=a; while true; do x="$x$x"; sleep 0.05; done`)
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	exitCtx := p.ExitContext()
+
+	require.ErrorIs(t, p.WatchRSS(ctx, 20*time.Millisecond, 32<<20), ErrRSSExceeded)
+	<-exitCtx.Done()
+	cause := context.Cause(exitCtx)
+	require.ErrorIs(t, cause, ErrProcessExited)
+	require.ErrorIs(t, cause, ErrRSSExceeded)
+	require.Contains(t, cause.Error(), "bash was killed: process memory limit exceeded: bash uses")
+}
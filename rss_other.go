@@ -0,0 +1,9 @@
+//go:build !linux
+
+package runner
+
+import "errors"
+
+func readRSS(int) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,100 @@
+// Package runnertest provides test helpers for the users of the runner package.
+package runnertest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// leakTimeout is how long AssertNoLeaks waits for the goroutines to exit.
+const leakTimeout = 2 * time.Second
+
+// ignoredStacks are parts of the stacks of the goroutines run by the runtime and the testing package.
+var ignoredStacks = []string{
+	"testing.tRunner",
+	"testing.(*T).Run",
+	"testing.runTests",
+	"testing.(*M).",
+	"os/signal.signal_recv",
+	"runtime.goexit0",
+	"runtime.ensureSigM",
+}
+
+// AssertNoLeaks snapshots the running goroutines and, when the test finishes, fails it if there are goroutines that
+// were started after the snapshot and are still running, e.g. readers or waiters leaked by a process run. The
+// goroutines of the runtime and the testing package are ignored. The goroutines are given a short time to exit
+// before they are reported with their stacks. It should be called at the beginning of the test, and does not work
+// with parallel tests.
+func AssertNoLeaks(tb testing.TB) {
+	tb.Helper()
+	baseline := map[string]bool{}
+	for _, g := range goroutines() {
+		baseline[g.id] = true
+	}
+	tb.Cleanup(func() {
+		tb.Helper()
+		var leaked []goroutine
+		deadline := time.Now().Add(leakTimeout)
+		for {
+			leaked = leaked[:0]
+			for _, g := range goroutines() {
+				if !baseline[g.id] && !g.ignored() {
+					leaked = append(leaked, g)
+				}
+			}
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if len(leaked) > 0 {
+			var stacks []string
+			for _, g := range leaked {
+				stacks = append(stacks, g.stack)
+			}
+			tb.Errorf("%d goroutine(s) leaked:\n%s", len(leaked), strings.Join(stacks, "\n\n"))
+		}
+	})
+}
+
+type goroutine struct {
+	id    string
+	stack string
+}
+
+func (g goroutine) ignored() bool {
+	for _, s := range ignoredStacks {
+		if strings.Contains(g.stack, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// goroutines returns all running goroutines, except the calling one.
+func goroutines() []goroutine {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	// The first one is the calling goroutine.
+	blocks := bytes.Split(buf, []byte("\n\n"))[1:]
+	result := make([]goroutine, 0, len(blocks))
+	for _, b := range blocks {
+		var id string
+		if _, err := fmt.Sscanf(string(b), "goroutine %s", &id); err != nil {
+			continue
+		}
+		result = append(result, goroutine{id: id, stack: string(b)})
+	}
+	return result
+}
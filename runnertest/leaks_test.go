@@ -0,0 +1,51 @@
+package runnertest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strotz/runner"
+)
+
+// fakeTB records errors and cleanup functions.
+type fakeTB struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func TestAssertNoLeaksDetectsLeak(t *testing.T) {
+	tb := &fakeTB{}
+	AssertNoLeaks(tb)
+	block := make(chan struct{})
+	defer close(block)
+	go func() {
+		<-block
+	}()
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+	require.Len(t, tb.errors, 1)
+	require.Contains(t, tb.errors[0], "1 goroutine(s) leaked")
+	require.Contains(t, tb.errors[0], "TestAssertNoLeaksDetectsLeak")
+}
+
+func TestAssertNoLeaksAfterProcessRun(t *testing.T) {
+	AssertNoLeaks(t)
+	p, err := runner.NewProcess(context.TODO(), "bash", "-c", "echo hello")
+	require.NoError(t, err)
+	require.NoError(t, p.Run())
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(context.TODO(), "hello"))
+}
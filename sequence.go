@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrSequenceMismatch is returned when the output does not match the expected sequence of lines.
+var ErrSequenceMismatch = errors.New("output does not match expected sequence")
+
+// ExpectSequence reads the output from the beginning and compares the lines with expected, in order. Every line
+// must be equal to the expected one. It returns nil once all expected lines are matched, or ErrSequenceMismatch
+// with a diff on the first mismatch or if the output is closed too early.
+func (s *AccumulatedOutput) ExpectSequence(ctx context.Context, expected []string) error {
+	return s.expectSequence(ctx, expected, false)
+}
+
+// ExpectSubsequence is similar to ExpectSequence, but lines that do not match the next expected line are skipped.
+// It fails only if the output is closed before all expected lines appear in order.
+func (s *AccumulatedOutput) ExpectSubsequence(ctx context.Context, expected []string) error {
+	return s.expectSequence(ctx, expected, true)
+}
+
+func (s *AccumulatedOutput) expectSequence(ctx context.Context, expected []string, skipUnmatched bool) error {
+	if len(expected) == 0 {
+		return nil
+	}
+	var matched []string
+	mismatch := ""
+	err := s.eachLine(ctx, func(line string) bool {
+		want := expected[len(matched)]
+		if line != want {
+			if skipUnmatched {
+				return true
+			}
+			mismatch = line
+			return false
+		}
+		matched = append(matched, line)
+		return len(matched) < len(expected)
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if len(matched) == len(expected) {
+		return nil
+	}
+	actual := fmt.Sprintf("%q", mismatch)
+	if errors.Is(err, io.EOF) {
+		actual = "<end of output>"
+	}
+	return fmt.Errorf("%w:\n%s", ErrSequenceMismatch, sequenceDiff(matched, expected[len(matched):], actual))
+}
+
+// sequenceDiff formats matched lines, the first missing expected line and the actual value in a diff like format.
+func sequenceDiff(matched []string, missing []string, actual string) string {
+	var b strings.Builder
+	for _, line := range matched {
+		fmt.Fprintf(&b, "  %q\n", line)
+	}
+	fmt.Fprintf(&b, "- %q\n", missing[0])
+	fmt.Fprintf(&b, "+ %s", actual)
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectSequence(t *testing.T) {
+	out := NewAccumulatedOutput(io.Discard)
+	_, err := out.Write([]byte("HELLO\nAUTH ok\nDEBUG noise\nREADY\n"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	ctx := context.TODO()
+
+	require.NoError(t, out.ExpectSequence(ctx, []string{"HELLO", "AUTH ok"}))
+
+	err = out.ExpectSequence(ctx, []string{"HELLO", "AUTH ok", "READY"})
+	require.ErrorIs(t, err, ErrSequenceMismatch)
+	require.Contains(t, err.Error(), `- "READY"`)
+	require.Contains(t, err.Error(), `+ "DEBUG noise"`)
+
+	require.NoError(t, out.ExpectSubsequence(ctx, []string{"HELLO", "READY"}))
+	err = out.ExpectSubsequence(ctx, []string{"READY", "HELLO"})
+	require.ErrorIs(t, err, ErrSequenceMismatch)
+	require.Contains(t, err.Error(), "+ <end of output>")
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Spec is the serializable specification of a process run. It makes it possible to record exactly what ran and to
+// run it again with NewProcessFromSpec. Env contains the variables set on the process, values of the secret
+// variables, listed in Secrets, are redacted unless the spec is created with SpecWithSecrets. The inherited
+// environment and its filter, stdin, hooks, e.g. OnExit, the temporary directory and the output settings, e.g.
+// WithTaggedOutput, are not recorded.
+type Spec struct {
+	// Name is the name of the command as passed to NewProcess.
+	Name string `json:"name"`
+	// Path is the resolved path of the executable, see ResolvedPath.
+	Path        string            `json:"path,omitempty"`
+	Args        []string          `json:"args,omitempty"`
+	Dir         string            `json:"dir,omitempty"`
+	Env         []string          `json:"env,omitempty"`
+	Secrets     []string          `json:"secrets,omitempty"`
+	CRMode      CRMode            `json:"cr_mode,omitempty"`
+	StreamClose StreamClose       `json:"stream_close,omitempty"`
+	WaitDelay   time.Duration     `json:"wait_delay,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	// ProcessGroup, PTY, Background and ReadinessFD record the options of the same names, e.g. WithProcessGroup.
+	ProcessGroup bool `json:"process_group,omitempty"`
+	PTY          bool `json:"pty,omitempty"`
+	Background   bool `json:"background,omitempty"`
+	ReadinessFD  bool `json:"readiness_fd,omitempty"`
+}
+
+// Spec returns the specification of the process, with the values of the secret variables redacted.
+func (p *Process) Spec() Spec {
+	spec := p.SpecWithSecrets()
+	for i, kv := range spec.Env {
+		spec.Env[i] = p.redactEnv(kv)
+	}
+	return spec
+}
+
+// SpecWithSecrets returns the specification of the process, including the values of the secret variables as is. The
+// spec should not be stored or printed where the secrets may leak.
+func (p *Process) SpecWithSecrets() Spec {
+	spec := Spec{
+		Name:         p.cmd.Args[0],
+		Args:         append([]string(nil), p.cmd.Args[1:]...),
+		Dir:          p.cmd.Dir,
+		Env:          p.envList(),
+		CRMode:       p.crMode,
+		StreamClose:  p.streamClose,
+		WaitDelay:    p.cmd.WaitDelay,
+		Tags:         p.printer.Tags,
+		ProcessGroup: p.processGroup,
+		PTY:          p.ptyMode,
+		Background:   p.background,
+		ReadinessFD:  p.readinessSlot != 0,
+	}
+	if path, err := p.ResolvedPath(); err == nil {
+		spec.Path = path
+	}
+	for name := range p.secrets {
+		spec.Secrets = append(spec.Secrets, name)
+	}
+	slices.Sort(spec.Secrets)
+	return spec
+}
+
+// NewProcessFromSpec returns a new process created from the specification. If the spec has the resolved path, it
+// is used as is, without looking up the name. The secret variables with redacted values, see Spec, are not set, they
+// should be set again with SetSecretEnv.
+func NewProcessFromSpec(ctx context.Context, spec Spec) (*Process, error) {
+	p, err := NewProcess(ctx, spec.Name, spec.Args...)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Path != "" {
+		p.cmd.Path = spec.Path
+		p.cmd.Err = nil
+	}
+	p.cmd.Dir = spec.Dir
+	for _, kv := range spec.Env {
+		name, value, _ := strings.Cut(kv, "=")
+		if value == redacted && slices.Contains(spec.Secrets, name) {
+			continue
+		}
+		p.AddEnv(name, value)
+	}
+	for _, name := range spec.Secrets {
+		if p.secrets == nil {
+			p.secrets = map[string]bool{}
+		}
+		p.secrets[name] = true
+	}
+	p.crMode = spec.CRMode
+	p.streamClose = spec.StreamClose
+	p.cmd.WaitDelay = spec.WaitDelay
+	p.printer.Tags = spec.Tags
+	var opts []Option
+	if spec.ProcessGroup {
+		opts = append(opts, WithProcessGroup())
+	}
+	if spec.PTY {
+		opts = append(opts, WithPTY())
+	}
+	if spec.Background {
+		opts = append(opts, WithBackground())
+	}
+	if spec.ReadinessFD {
+		opts = append(opts, WithReadinessFD())
+	}
+	if err := p.Apply(opts...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecRoundTrip(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+	p, err := NewProcess(ctx, "bash", "-c", `echo "$GREETING from $(pwd)"`)
+	require.NoError(t, err)
+	p.ChangeDirectory(dir)
+	p.AddEnv("GREETING", "hello")
+	p.SetSecretEnv("TOKEN", "secret")
+	p.SetTags(map[string]string{"env": "test"})
+	require.NoError(t, p.Run())
+	expected, err := p.ReadStdOut()
+	require.NoError(t, err)
+
+	data, err := json.Marshal(p.SpecWithSecrets())
+	require.NoError(t, err)
+	var spec Spec
+	require.NoError(t, json.Unmarshal(data, &spec))
+	require.Equal(t, p.SpecWithSecrets(), spec)
+	require.Equal(t, []string{"TOKEN"}, spec.Secrets)
+
+	replay, err := NewProcessFromSpec(ctx, spec)
+	require.NoError(t, err)
+	require.Equal(t, p.String(), replay.String())
+	require.NoError(t, replay.Run())
+	actual, err := replay.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"hello from " + dir}, actual)
+	require.Equal(t, expected, actual)
+}
+
+func TestSpecRedactsSecrets(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", `echo "token=$TOKEN"`)
+	require.NoError(t, err)
+	p.AddEnv("GREETING", "hello")
+	p.SetSecretEnv("TOKEN", "s3cr3t")
+	require.NoError(t, p.Apply(WithProcessGroup(), WithBackground()))
+
+	spec := p.Spec()
+	data, err := json.Marshal(spec)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "s3cr3t")
+	require.Equal(t, []string{"GREETING=hello", "TOKEN=***"}, spec.Env)
+	require.True(t, spec.ProcessGroup)
+	require.True(t, spec.Background)
+	require.False(t, spec.PTY)
+
+	// The redacted secret is not set, it is set again by the caller.
+	replay, err := NewProcessFromSpec(ctx, spec)
+	require.NoError(t, err)
+	require.Equal(t, []string{"GREETING=hello"}, replay.envList())
+	require.True(t, replay.processGroup)
+	require.True(t, replay.background)
+	replay.SetSecretEnv("TOKEN", "s3cr3t")
+	require.Equal(t, p.String(), replay.String())
+}
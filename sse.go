@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ServeSSE is an http.HandlerFunc that streams the combined output of the process as Server-Sent Events, one event
+// per line. It sends the lines accumulated so far, then follows new lines until the client disconnects or the
+// output is closed.
+func (p *Process) ServeSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	_, _, combined := p.outputs()
+	_ = combined.eachLine(r.Context(), func(line string) bool {
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", line); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	})
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeSSE(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c", "echo one && sleep 0.1 && echo two 1>&2 && sleep 0.1 && echo three")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(p.ServeSSE))
+	defer server.Close()
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{"one", "two", "three"}, events)
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+	"time"
+)
+
+// SetStopSequence sets how the process is stopped when its context is done: sig is sent first and, if the process
+// is still running after grace, it is killed with os.Kill, e.g. SIGTERM with a few seconds to shut down cleanly. By
+// default the process is killed with os.Kill right away. The process stopped this way exits with ReasonCanceled, even
+// if it exits cleanly on sig, and the cause of the context, see context.Cause, is reported by ExitContext. Only
+// os.Kill is supported on Windows. It should be called before the process is started.
+func (p *Process) SetStopSequence(sig os.Signal, grace time.Duration) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.stopSignal = sig
+	p.stopGrace = grace
+}
+
+// watchContext stops the process when its context is done before the process exits, i.e. before done is closed.
+func (p *process) watchContext(done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-p.ctx.Done():
+	}
+	select {
+	case <-done:
+		// Both happened, the exit is not caused by the context.
+		return
+	default:
+	}
+	p.m.Lock()
+	p.stopped = true
+	p.m.Unlock()
+	logCancel(p.shortName)
+	p.stop(done)
+}
+
+// stop runs the stop sequence on context cancellation, see SetStopSequence. Unlike KillWith, it does not mark the
+// process as killed.
+func (p *process) stop(done <-chan struct{}) {
+	p.m.Lock()
+	sig, grace := p.stopSignal, p.stopGrace
+	p.m.Unlock()
+	if sig == nil {
+		sig = os.Kill
+	}
+	_ = p.escalate(context.Background(), done, sig, grace)
+}
+
+// DefaultStopGrace is the time Stop waits for the process to exit after SIGTERM, unless set with SetStopSequence.
+const DefaultStopGrace = 5 * time.Second
+
+// Stop terminates the process gracefully, so it can flush its state: it sends SIGTERM and kills the process with
+// os.Kill if it is still running after DefaultStopGrace or when ctx is done. The signal and the grace period set with
+// SetStopSequence are used instead, if set. Like KillWith, it marks the process as killed. It returns when the
+// process exits, with the context error if ctx was done first. On Windows the process is killed right away.
+func (p *Process) Stop(ctx context.Context) error {
+	proc := p.osProcess()
+	if proc == nil {
+		return fmt.Errorf("%w: %s", ErrNotStarted, p.shortName)
+	}
+	p.m.Lock()
+	sig, grace := p.stopSignal, p.stopGrace
+	done := p.done
+	p.killed = true
+	p.m.Unlock()
+	if sig == nil {
+		sig, grace = syscall.SIGTERM, DefaultStopGrace
+	}
+	log.Println("Stopping process:", p.shortName, proc.Pid, "with", sig)
+	err := p.escalate(ctx, done, sig, grace)
+	<-done
+	return err
+}
+
+// escalate sends sig to the process and kills it with os.Kill if it does not exit, i.e. done is not closed, within
+// grace or before ctx is done. It returns the context error if ctx is done first.
+func (p *process) escalate(ctx context.Context, done <-chan struct{}, sig os.Signal, grace time.Duration) error {
+	if sig == os.Kill {
+		p.signal(os.Kill)
+		return nil
+	}
+	if err := p.terminate(sig); err != nil {
+		if !errors.Is(err, os.ErrProcessDone) {
+			// E.g. the signal is not supported on the platform.
+			log.Printf("failed to send %s to process '%s', killing it: %v", sig, p.shortName, err)
+			p.signal(os.Kill)
+		}
+		return nil
+	}
+	t := time.NewTimer(grace)
+	defer t.Stop()
+	// The members of the process group may outlive the leader, they get the same grace period.
+	tick := time.NewTicker(groupPollInterval)
+	defer tick.Stop()
+	var poll <-chan time.Time
+	for {
+		select {
+		case <-done:
+			if !p.groupAlive() {
+				return nil
+			}
+			done, poll = nil, tick.C
+		case <-poll:
+			if !p.groupAlive() {
+				return nil
+			}
+		case <-t.C:
+			log.Printf("process '%s' did not stop within %s after %s, killing it", p.shortName, grace, sig)
+			p.signal(os.Kill)
+			return nil
+		case <-ctx.Done():
+			p.signal(os.Kill)
+			return ctx.Err()
+		}
+	}
+}
+
+// groupPollInterval is the interval at which the stop sequence checks whether the process group is gone.
+const groupPollInterval = 50 * time.Millisecond
+
+// signal sends sig to the process, the error of the process that already exited is ignored.
+func (p *process) signal(sig os.Signal) {
+	if err := p.terminate(sig); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		log.Printf("failed to send %s to process '%s': %v", sig, p.shortName, err)
+	}
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// StreamClose selects when the output streams of the process are closed after it exits.
+type StreamClose int
+
+const (
+	// CloseOnWait closes the output streams when cmd.Wait returns. It is the default. Wait returns only after the
+	// pipes are drained, so a grandchild that inherited the pipes delays the exit until it closes them or exits. Use
+	// SetWaitDelay to bound the delay, the output written after it is dropped. It applies also to the pipes drained
+	// by the process itself, e.g. with WithTaggedOutput or WithPTY.
+	CloseOnWait StreamClose = iota
+	// CloseOnEOF reports the exit as soon as the process exits, but keeps the output streams open until the pipes
+	// reach EOF, i.e. until every process that inherited them closes them. Nothing is dropped, but the readers of
+	// the output may block after the exit.
+	CloseOnEOF
+)
+
+// SetStreamClose sets when the output streams are closed. It should be called before the process is started.
+func (p *Process) SetStreamClose(mode StreamClose) {
+	p.streamClose = mode
+}
+
+// SetWaitDelay bounds the time the exit waits for the pipes to be drained after the process exits, see
+// exec.Cmd.WaitDelay. When the delay expires the pipes are closed and the exit is reported as successful if the
+// process itself exited with zero code. It has no effect with CloseOnEOF.
+func (p *Process) SetWaitDelay(d time.Duration) {
+	p.cmd.WaitDelay = d
+}
+
+// awaitDrained waits until the pipes owned by the process are drained, e.g. with WithTaggedOutput or WithPTY. Like
+// exec.Cmd.WaitDelay for the pipes owned by the command, the wait is bounded by the wait delay, then the read ends
+// are closed and the output written after it is dropped.
+func (p *process) awaitDrained(drained <-chan struct{}) {
+	if p.cmd.WaitDelay <= 0 || p.abortDrain == nil {
+		<-drained
+		return
+	}
+	t := time.NewTimer(p.cmd.WaitDelay)
+	defer t.Stop()
+	select {
+	case <-drained:
+	case <-t.C:
+		log.Printf("output of process '%s' is not drained within %s after exit, closing it", p.shortName,
+			p.cmd.WaitDelay)
+		p.abortDrain()
+		<-drained
+	}
+}
+
+// closeOutputs closes the output streams.
+func (p *process) closeOutputs() {
+	_ = p.stdout.Close()
+	_ = p.stderr.Close()
+	_ = p.combined.Close()
+}
+
+// eofPipes creates the pipes and configures the command to write to them, so cmd.Wait does not wait for them to be
+// drained. The returned function should be called after the command is started. The returned channel is closed when
+// both pipes reach EOF.
+func eofPipes(p *process) (func(), <-chan struct{}, error) {
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		_ = stdoutR.Close()
+		_ = stdoutW.Close()
+		return nil, nil, err
+	}
+	stdout, stderr := p.cmd.Stdout, p.cmd.Stderr
+	p.cmd.Stdout = stdoutW
+	p.cmd.Stderr = stderrW
+	drained := make(chan struct{})
+	return func() {
+		// The child has its own copies of the write ends.
+		_ = stdoutW.Close()
+		_ = stderrW.Close()
+		var wg sync.WaitGroup
+		copyPipe := func(dst io.Writer, r *os.File) {
+			defer wg.Done()
+			defer r.Close()
+			_, _ = io.Copy(dst, r)
+		}
+		wg.Add(2)
+		go copyPipe(stdout, stdoutR)
+		go copyPipe(stderr, stderrR)
+		go func() {
+			wg.Wait()
+			close(drained)
+		}()
+	}, drained, nil
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamCloseCleanExit(t *testing.T) {
+	for _, mode := range []StreamClose{CloseOnWait, CloseOnEOF} {
+		p, err := NewProcess(context.TODO(), "bash", "-c", "echo one && echo two 1>&2")
+		require.NoError(t, err)
+		p.SetStreamClose(mode)
+		require.NoError(t, p.Start())
+		<-p.done
+		stdout, err := p.ReadStdOut()
+		require.NoError(t, err)
+		require.Equal(t, []string{"one"}, stdout)
+		stderr, err := p.ReadStdErr()
+		require.NoError(t, err)
+		require.Equal(t, []string{"two"}, stderr)
+		require.Equal(t, ReasonSuccess, p.ExitReason())
+	}
+}
+
+// grandchild leaves a background process that holds the output pipes after the parent exits.
+const grandchild = "echo parent && (sleep 0.5 && echo grandchild) &"
+
+func TestStreamCloseOnWaitDropsLateOutput(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", grandchild)
+	require.NoError(t, err)
+	p.SetWaitDelay(100 * time.Millisecond)
+	start := time.Now()
+	require.NoError(t, p.Start())
+	<-p.done
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+	require.Equal(t, ReasonSuccess, p.ExitReason())
+	stdout, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"parent"}, stdout)
+}
+
+func TestStreamCloseOnEOFKeepsLateOutput(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", grandchild)
+	require.NoError(t, err)
+	p.SetStreamClose(CloseOnEOF)
+	start := time.Now()
+	require.NoError(t, p.Start())
+	<-p.done
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+	require.Equal(t, ReasonSuccess, p.ExitReason())
+	stdout, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Equal(t, []string{"parent", "grandchild"}, stdout)
+	require.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+}
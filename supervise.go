@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RestartMode selects which exits restart the supervised process, see RestartPolicy.
+type RestartMode int
+
+const (
+	// RestartNever does not restart the process.
+	RestartNever RestartMode = iota
+	// RestartOnFailure restarts the process after an unexpected exit, i.e. a non-zero exit code or a crash.
+	RestartOnFailure
+	// RestartAlways restarts the process after any exit, including a successful one.
+	RestartAlways
+)
+
+// RestartPolicy describes how SuperviseWithPolicy restarts the process. The process killed with Kill, KillWith or
+// Stop, or canceled by its context, is never restarted.
+type RestartPolicy struct {
+	Mode RestartMode
+	// MaxRestarts is the number of restarts after which supervision gives up, negative means no limit.
+	MaxRestarts int
+	// Backoff is the delay before the first restart, it doubles after each restart up to MaxBackoff, unless
+	// MaxBackoff is zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// OnRestart, when set, is called before each restart, after the backoff.
+	OnRestart func(RestartEvent)
+}
+
+// RestartEvent describes the exit that caused a restart.
+type RestartEvent struct {
+	// Restart is the number of the restart, starting with 1.
+	Restart  int
+	ExitCode int
+	Reason   ExitReason
+	// Err is the exit error, nil after a successful exit.
+	Err error
+	// Backoff is the delay that preceded the restart.
+	Backoff time.Duration
+}
+
+// Supervise keeps the process running: after each unexpected exit, i.e. a non-zero exit code or a crash, it waits
+// for backoff and restarts the process, the backoff doubles after each restart. The process is started if it is not
+// started yet. Supervise returns nil when the process exits successfully or is killed, the context error when ctx is
+// done, or the last exit error when the process exits unexpectedly after maxRestarts restarts. See Restarts and
+// LastError, and SuperviseWithPolicy for more control.
+func (p *Process) Supervise(ctx context.Context, maxRestarts int, backoff time.Duration) error {
+	return p.SuperviseWithPolicy(ctx, RestartPolicy{
+		Mode:        RestartOnFailure,
+		MaxRestarts: maxRestarts,
+		Backoff:     backoff,
+	})
+}
+
+// SuperviseWithPolicy keeps the process running according to the policy. The process is started if it is not
+// started yet. It returns nil when the process exits and the policy does not restart it after such exit, the context
+// error when ctx is done, or, after MaxRestarts restarts, the last exit error.
+func (p *Process) SuperviseWithPolicy(ctx context.Context, policy RestartPolicy) error {
+	if p.Status() == StatusNotStarted {
+		if err := p.Start(); err != nil {
+			return err
+		}
+	}
+	backoff := policy.Backoff
+	for {
+		select {
+		case <-p.Done():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		reason := p.ExitReason()
+		switch {
+		case reason == ReasonKilled:
+			return nil
+		case reason == ReasonCanceled:
+			return p.ctx.Err()
+		case policy.Mode == RestartNever:
+			return p.ExitError()
+		case reason == ReasonSuccess && policy.Mode != RestartAlways:
+			return nil
+		}
+		err := p.ExitError()
+		p.m.Lock()
+		if err != nil {
+			p.lastErr = err
+		}
+		restarts := p.restarts
+		p.m.Unlock()
+		if policy.MaxRestarts >= 0 && restarts >= policy.MaxRestarts {
+			if err == nil {
+				return nil
+			}
+			return fmt.Errorf("%s gave up after %d restarts: %w", p.shortName, restarts, err)
+		}
+		if err != nil {
+			log.Printf("process '%s' exited unexpectedly: %v, restarting in %s", p.shortName, err, backoff)
+		} else {
+			log.Printf("process '%s' exited, restarting in %s", p.shortName, backoff)
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		event := RestartEvent{
+			Restart:  restarts + 1,
+			ExitCode: p.ExitCode(),
+			Reason:   reason,
+			Err:      err,
+			Backoff:  backoff,
+		}
+		backoff *= 2
+		if policy.MaxBackoff > 0 {
+			backoff = min(backoff, policy.MaxBackoff)
+		}
+		p.m.Lock()
+		p.restarts++
+		p.m.Unlock()
+		if policy.OnRestart != nil {
+			policy.OnRestart(event)
+		}
+		if err := p.Restart(); err != nil {
+			return err
+		}
+	}
+}
+
+// Restarts returns the number of restarts made by Supervise.
+func (p *Process) Restarts() int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.restarts
+}
+
+// LastError returns the error of the last unexpected exit seen by Supervise, or nil.
+func (p *Process) LastError() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.lastErr
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuperviseRestartsCrashedProcess(t *testing.T) {
+	ctx := context.TODO()
+	// Every run increments the counter, first two runs crash.
+	counter := filepath.Join(t.TempDir(), "runs")
+	script := "n=$(cat " + counter + " 2>/dev/null || echo 0); n=$((n+1)); echo $n > " + counter +
+		"; if [ $n -le 2 ]; then echo crash $n 1>&2; exit 1; fi; echo up; sleep 30"
+	p, err := NewProcess(ctx, "bash", "-c", script)
+	require.NoError(t, err)
+	result := make(chan error)
+	go func() {
+		result <- p.Supervise(ctx, 3, 10*time.Millisecond)
+	}()
+	require.Eventually(t, func() bool {
+		return p.Restarts() == 2 && p.Status() == StatusRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, p.StdOutScanner().WaitForKeyword(ctx, "up"))
+	require.ErrorContains(t, p.LastError(), "exit status 1")
+
+	require.NoError(t, p.KillWith(os.Kill))
+	require.NoError(t, <-result)
+	require.Equal(t, 2, p.Restarts())
+}
+
+func TestSuperviseGivesUp(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "exit 4")
+	require.NoError(t, err)
+	err = p.Supervise(context.TODO(), 2, time.Millisecond)
+	require.ErrorContains(t, err, "gave up after 2 restarts")
+	require.Equal(t, 2, p.Restarts())
+	require.Equal(t, 4, p.ExitCode())
+}
+
+func TestSuperviseWithPolicyAlways(t *testing.T) {
+	var events []RestartEvent
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo run")
+	require.NoError(t, err)
+	err = p.SuperviseWithPolicy(context.TODO(), RestartPolicy{
+		Mode:        RestartAlways,
+		MaxRestarts: 3,
+		Backoff:     10 * time.Millisecond,
+		MaxBackoff:  25 * time.Millisecond,
+		OnRestart: func(e RestartEvent) {
+			events = append(events, e)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, p.Restarts())
+	require.Len(t, events, 3)
+	for i, e := range events {
+		require.Equal(t, i+1, e.Restart)
+		require.Equal(t, ReasonSuccess, e.Reason)
+		require.NoError(t, e.Err)
+	}
+	require.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond},
+		[]time.Duration{events[0].Backoff, events[1].Backoff, events[2].Backoff})
+}
+
+func TestSuperviseWithPolicyOnFailure(t *testing.T) {
+	var events []RestartEvent
+	p, err := NewProcess(context.TODO(), "bash", "-c", "exit 2")
+	require.NoError(t, err)
+	err = p.SuperviseWithPolicy(context.TODO(), RestartPolicy{
+		Mode:        RestartOnFailure,
+		MaxRestarts: 2,
+		Backoff:     time.Millisecond,
+		OnRestart: func(e RestartEvent) {
+			events = append(events, e)
+		},
+	})
+	require.ErrorContains(t, err, "gave up after 2 restarts")
+	require.Len(t, events, 2)
+	require.Equal(t, 2, events[0].ExitCode)
+	require.Equal(t, ReasonFailure, events[0].Reason)
+	require.Error(t, events[0].Err)
+
+	// A successful exit is not restarted.
+	p, err = NewProcess(context.TODO(), "bash", "-c", "exit 0")
+	require.NoError(t, err)
+	require.NoError(t, p.SuperviseWithPolicy(context.TODO(), RestartPolicy{Mode: RestartOnFailure, MaxRestarts: -1}))
+	require.Zero(t, p.Restarts())
+}
+
+func TestSuperviseWithPolicyNever(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "exit 3")
+	require.NoError(t, err)
+	err = p.SuperviseWithPolicy(context.TODO(), RestartPolicy{Mode: RestartNever})
+	var ee *ExitError
+	require.ErrorAs(t, err, &ee)
+	require.Equal(t, 3, ee.ExitCode())
+	require.Zero(t, p.Restarts())
+}
+
+func TestSuperviseConcurrentReads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo tick && sleep 0.02")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	supervised := make(chan error, 1)
+	go func() {
+		supervised <- p.SuperviseWithPolicy(ctx, RestartPolicy{Mode: RestartAlways, MaxRestarts: 20})
+	}()
+	// The readers see one of the runs, each run prints the keyword.
+	for p.Restarts() < 10 {
+		wait, cancelWait := context.WithTimeout(ctx, 5*time.Second)
+		require.NoError(t, p.StdOutScanner().WaitForKeyword(wait, "tick"))
+		require.NoError(t, p.CombinedScanner().WaitForKeyword(wait, "tick"))
+		require.NoError(t, p.WaitForExit(wait))
+		cancelWait()
+		_ = p.IsAlive()
+		_ = p.Summary()
+		_, _ = p.DetachOutput()
+	}
+	require.NoError(t, <-supervised)
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// Stream identifies the output stream of the process.
+type Stream string
+
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+// TaggedLine is a line of the process output tagged with the stream it came from.
+type TaggedLine struct {
+	Stream Stream
+	Line   string
+}
+
+// WithTaggedOutput switches the process to read stdout and stderr from its own pipes and drain them in a single
+// goroutine, that records the lines in the order of arrival tagged by the source stream. See TaggedLines.
+func WithTaggedOutput() Option {
+	return func(p *Process) error {
+		p.tagged = newTaggedOutput(p.cmd.Stdout, p.cmd.Stderr)
+		return nil
+	}
+}
+
+// TaggedLines returns the lines of stdout and stderr in the order of arrival, tagged by the source stream. It
+// requires WithTaggedOutput, otherwise it returns nil. Incomplete last lines are included after the process exits.
+func (p *Process) TaggedLines() []TaggedLine {
+	p.m.Lock()
+	tagged := p.tagged
+	p.m.Unlock()
+	if tagged == nil {
+		return nil
+	}
+	tagged.m.Lock()
+	defer tagged.m.Unlock()
+	return append([]TaggedLine(nil), tagged.lines...)
+}
+
+// taggedOutput drains stdout and stderr pipes and records tagged lines.
+type taggedOutput struct {
+	dst map[Stream]io.Writer
+	// done is closed when both pipes are drained.
+	done chan struct{}
+
+	m       sync.Mutex
+	partial map[Stream][]byte
+	lines   []TaggedLine
+}
+
+func newTaggedOutput(stdout, stderr io.Writer) *taggedOutput {
+	return &taggedOutput{
+		dst: map[Stream]io.Writer{
+			StreamStdout: stdout,
+			StreamStderr: stderr,
+		},
+		partial: map[Stream][]byte{},
+		done:    make(chan struct{}),
+	}
+}
+
+type taggedChunk struct {
+	stream Stream
+	data   []byte
+}
+
+// pipes creates the pipes and configures the command to write to them. The returned function should be called
+// after the command is started.
+func (t *taggedOutput) pipes(p *process) (func(), error) {
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		_ = stdoutR.Close()
+		_ = stdoutW.Close()
+		return nil, err
+	}
+	p.cmd.Stdout = stdoutW
+	p.cmd.Stderr = stderrW
+	p.abortDrain = func() {
+		_ = stdoutR.Close()
+		_ = stderrR.Close()
+	}
+	return func() {
+		// The child has its own copies of the write ends.
+		_ = stdoutW.Close()
+		_ = stderrW.Close()
+		go t.drain(stdoutR, stderrR)
+	}, nil
+}
+
+// drain reads both pipes until EOF and processes the chunks in one goroutine in the order they are read.
+func (t *taggedOutput) drain(stdout, stderr *os.File) {
+	chunks := make(chan taggedChunk)
+	var readers sync.WaitGroup
+	read := func(stream Stream, r *os.File) {
+		defer readers.Done()
+		defer r.Close()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				chunks <- taggedChunk{stream: stream, data: append([]byte(nil), buf[:n]...)}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	readers.Add(2)
+	go read(StreamStdout, stdout)
+	go read(StreamStderr, stderr)
+	go func() {
+		readers.Wait()
+		close(chunks)
+	}()
+	for c := range chunks {
+		_, _ = t.dst[c.stream].Write(c.data)
+		t.record(c.stream, c.data)
+	}
+	t.flush()
+	close(t.done)
+}
+
+func (t *taggedOutput) record(stream Stream, data []byte) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	data = append(t.partial[stream], data...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		t.lines = append(t.lines, TaggedLine{Stream: stream, Line: string(data[:i])})
+		data = data[i+1:]
+	}
+	t.partial[stream] = append([]byte(nil), data...)
+}
+
+func (t *taggedOutput) flush() {
+	t.m.Lock()
+	defer t.m.Unlock()
+	for _, stream := range []Stream{StreamStdout, StreamStderr} {
+		if len(t.partial[stream]) > 0 {
+			t.lines = append(t.lines, TaggedLine{Stream: stream, Line: string(t.partial[stream])})
+			t.partial[stream] = nil
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaggedOutputOrdering(t *testing.T) {
+	ctx := context.TODO()
+	p, err := NewProcess(ctx, "bash", "-c",
+		"for i in 1 2 3 4 5 6 7 8; do echo out$i; sleep 0.02; echo err$i 1>&2; sleep 0.02; done")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithTaggedOutput()))
+	var wg sync.WaitGroup
+	require.NoError(t, p.StartAsync(&wg))
+	wg.Wait()
+
+	var expected []TaggedLine
+	for i := 1; i <= 8; i++ {
+		expected = append(expected,
+			TaggedLine{Stream: StreamStdout, Line: fmt.Sprintf("out%d", i)},
+			TaggedLine{Stream: StreamStderr, Line: fmt.Sprintf("err%d", i)})
+	}
+	lines := p.TaggedLines()
+	require.Len(t, lines, len(expected))
+	inOrder := 0
+	for i := range expected {
+		if lines[i] == expected[i] {
+			inOrder++
+		}
+	}
+	require.Equal(t, len(expected), inOrder, "ordering fidelity %d/%d", inOrder, len(expected))
+
+	// Regular outputs are still populated.
+	stdout, err := p.ReadStdOut()
+	require.NoError(t, err)
+	require.Len(t, stdout, 8)
+	require.NoError(t, p.CombinedScanner().WaitForKeyword(ctx, "err8"))
+}
+
+func TestTaggedOutputWaitDelay(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", grandchild)
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithTaggedOutput()))
+	p.SetWaitDelay(100 * time.Millisecond)
+	start := time.Now()
+	require.NoError(t, p.Start())
+	<-p.Done()
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+	require.Equal(t, ReasonSuccess, p.ExitReason())
+	require.Equal(t, []TaggedLine{{Stream: StreamStdout, Line: "parent"}}, p.TaggedLines())
+}
@@ -0,0 +1,57 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+)
+
+// ServeTailSocket listens on the Unix domain socket at path and serves the combined output of the process to every
+// client that connects: it sends the lines accumulated so far, then follows new lines until the client disconnects
+// or the output is closed. It returns after the listener is created, the connections are served in background until
+// ctx is done, then the socket is removed.
+func (p *Process) ServeTailSocket(ctx context.Context, path string) error {
+	var lc net.ListenConfig
+	l, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Println(p.shortName, "tail socket error:", err)
+				}
+				return
+			}
+			go p.serveTail(ctx, conn)
+		}
+	}()
+	return nil
+}
+
+// serveTail streams the combined output to conn until the client disconnects, the output is closed or ctx is done.
+func (p *process) serveTail(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// The client is not expected to send anything, the read returns when it disconnects.
+		_, _ = io.Copy(io.Discard, conn)
+		cancel()
+	}()
+	_, _, combined := p.outputs()
+	_ = combined.eachLine(ctx, func(line string) bool {
+		_, err := io.WriteString(conn, line+"\n")
+		return err == nil
+	})
+}
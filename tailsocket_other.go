@@ -0,0 +1,13 @@
+//go:build !unix
+
+package runner
+
+import (
+	"context"
+	"errors"
+)
+
+// ServeTailSocket is not supported on this platform.
+func (p *Process) ServeTailSocket(context.Context, string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package runner
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeTailSocket(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	// Unix socket paths are limited in length, so the temp dir of the test may be too long.
+	dir, err := os.MkdirTemp("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tail.sock")
+
+	p, err := NewProcess(ctx, "bash", "-c", "echo history && sleep 0.3 && echo live")
+	require.NoError(t, err)
+	require.NoError(t, p.Start())
+	require.NoError(t, p.CombinedScanner().WaitForKeyword(ctx, "history"))
+	require.NoError(t, p.ServeTailSocket(ctx, path))
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	var lines []string
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{"history", "live"}, lines)
+}
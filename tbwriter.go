@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+// TBWriter returns a writer that logs each line with tb.Log, so the output is attributed to the test and shown by
+// go test with the test output. An incomplete last line is logged when the test finishes, the writes after that
+// are dropped, because testing.TB does not allow logging after the test completes. If tb is nil, the writer is
+// os.Stderr. Use it with Process.SetOutput.
+func TBWriter(tb testing.TB) io.Writer {
+	if tb == nil {
+		return os.Stderr
+	}
+	w := &tbWriter{tb: tb}
+	tb.Cleanup(w.finish)
+	return w
+}
+
+type tbWriter struct {
+	tb      testing.TB
+	m       sync.Mutex
+	partial []byte
+	done    bool
+}
+
+func (w *tbWriter) Write(p []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.done {
+		return len(p), nil
+	}
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.tb.Log(string(data[:i]))
+		data = data[i+1:]
+	}
+	w.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// finish logs the incomplete last line and stops logging.
+func (w *tbWriter) finish() {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if len(w.partial) > 0 {
+		w.tb.Log(string(w.partial))
+		w.partial = nil
+	}
+	w.done = true
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTB records logged lines and cleanup functions.
+type fakeTB struct {
+	testing.TB
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Log(args ...any) {
+	f.logs = append(f.logs, args[0].(string))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func TestTBWriter(t *testing.T) {
+	tb := &fakeTB{}
+	w := TBWriter(tb)
+	_, err := w.Write([]byte("one\ntw"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("o\nthr"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two"}, tb.logs)
+
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+	require.Equal(t, []string{"one", "two", "thr"}, tb.logs)
+	_, err = w.Write([]byte("late\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two", "thr"}, tb.logs)
+
+	require.Equal(t, os.Stderr, TBWriter(nil))
+}
+
+func TestProcessOutputToTB(t *testing.T) {
+	tb := &fakeTB{}
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo hello")
+	require.NoError(t, err)
+	p.SetOutput(TBWriter(tb))
+	require.NoError(t, p.Start())
+	require.NoError(t, p.WaitForExit(context.TODO()))
+	require.Contains(t, tb.logs, "bash            | hello")
+}
@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TimedLine is a line of the output with the time it was written.
+type TimedLine struct {
+	Time time.Time
+	Line string
+}
+
+// SetClock replaces the clock used to timestamp the lines. It should be called before the first write.
+func (s *AccumulatedOutput) SetClock(c Clock) {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	s.clock = c
+}
+
+// SetTimedLines enables recording of the lines with the time they were written, see TimedLines. It is off by
+// default, because the recorded lines are a copy of the whole output that is not limited by WithMaxLines. Only the
+// lines written after the call are recorded, so it should be called before the first write.
+func (s *AccumulatedOutput) SetTimedLines(enabled bool) {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	s.timed = enabled
+}
+
+// TimedLines returns the complete lines written so far with the time they were completed, i.e. the time when the
+// new line character was written. An incomplete last line is included only after the output is closed. It is empty
+// unless recording is enabled with SetTimedLines.
+func (s *AccumulatedOutput) TimedLines() []TimedLine {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	return append([]TimedLine(nil), s.lines...)
+}
+
+// recordLines splits written data to timed lines.
+func (s *AccumulatedOutput) recordLines(p []byte) {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	if !s.timed {
+		return
+	}
+	now := s.clock.Now()
+	data := append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		s.lines = append(s.lines, TimedLine{Time: now, Line: string(data[:i])})
+		data = data[i+1:]
+	}
+	s.partial = append([]byte(nil), data...)
+}
+
+// flushLines records the incomplete last line, if any.
+func (s *AccumulatedOutput) flushLines() {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	if len(s.partial) > 0 {
+		s.lines = append(s.lines, TimedLine{Time: s.clock.Now(), Line: string(s.partial)})
+		s.partial = nil
+	}
+	s.linesClosed = true
+}
+
+// linesSince returns the timed lines starting from index i and whether the output is closed, i.e. no more lines
+// are expected.
+func (s *AccumulatedOutput) linesSince(i int) ([]TimedLine, bool) {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	if i >= len(s.lines) {
+		return nil, s.linesClosed
+	}
+	return append([]TimedLine(nil), s.lines[i:]...), s.linesClosed
+}
+
+// Record writes the timed lines accumulated so far to w. Each line is framed as 8 bytes of Unix time in
+// nanoseconds, 4 bytes of line length and the line itself, integers are big endian. Use LoadRecording to read it
+// back.
+func (s *AccumulatedOutput) Record(w io.Writer) error {
+	var header [12]byte
+	for _, l := range s.TimedLines() {
+		binary.BigEndian.PutUint64(header[:8], uint64(l.Time.UnixNano()))
+		binary.BigEndian.PutUint32(header[8:], uint32(len(l.Line)))
+		if _, err := w.Write(header[:]); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, l.Line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadRecording reads timed lines written by Record.
+func LoadRecording(r io.Reader) ([]TimedLine, error) {
+	var result []TimedLine
+	var header [12]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return result, nil
+			}
+			return result, fmt.Errorf("failed to read recording header: %w", err)
+		}
+		ts := int64(binary.BigEndian.Uint64(header[:8]))
+		line := make([]byte, binary.BigEndian.Uint32(header[8:]))
+		if _, err := io.ReadFull(r, line); err != nil {
+			return result, fmt.Errorf("failed to read recorded line: %w", io.ErrUnexpectedEOF)
+		}
+		result = append(result, TimedLine{Time: time.Unix(0, ts), Line: string(line)})
+	}
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock returns preset time, that can be advanced manually.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	out := NewAccumulatedOutput(io.Discard)
+	out.SetClock(clock)
+	_, err := out.Write([]byte("not recorded\n"))
+	require.NoError(t, err)
+	require.Empty(t, out.TimedLines())
+	out.SetTimedLines(true)
+	_, err = out.Write([]byte("first\nsec"))
+	require.NoError(t, err)
+	clock.Advance(1500 * time.Millisecond)
+	_, err = out.Write([]byte("ond\n"))
+	require.NoError(t, err)
+	clock.Advance(time.Second)
+	_, err = out.Write([]byte("partial"))
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	expected := []TimedLine{
+		{Time: time.Unix(1700000000, 0), Line: "first"},
+		{Time: time.Unix(1700000001, 500000000), Line: "second"},
+		{Time: time.Unix(1700000002, 500000000), Line: "partial"},
+	}
+	require.Equal(t, expected, out.TimedLines())
+
+	var recording bytes.Buffer
+	require.NoError(t, out.Record(&recording))
+	loaded, err := LoadRecording(&recording)
+	require.NoError(t, err)
+	require.Equal(t, expected, loaded)
+
+	_, err = LoadRecording(bytes.NewReader([]byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 5, 'a'}))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestWithTimedLines(t *testing.T) {
+	p, err := NewProcess(context.TODO(), "bash", "-c", "echo one && echo two 1>&2")
+	require.NoError(t, err)
+	require.NoError(t, p.Apply(WithTimedLines()))
+	require.NoError(t, p.Run())
+	require.Len(t, p.stdout.TimedLines(), 1)
+	require.Len(t, p.stderr.TimedLines(), 1)
+	require.Len(t, p.combined.TimedLines(), 2)
+}